@@ -0,0 +1,142 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backgroundQueryTimeout is how long a terminal is waited for when querying
+// its background color.
+const backgroundQueryTimeout = 100 * time.Millisecond
+
+// ttyPath is the path of the terminal the background color is queried through.
+var ttyPath = "/dev/tty"
+
+// DetectTheme returns the Light theme if out is a terminal with a light
+// background, or the Default theme otherwise, including when the background
+// can't be determined.
+//
+// The background is determined from the COLORFGBG environment variable if
+// set, or by querying the terminal with an OSC 11 escape sequence, waiting
+// at most 100ms for its answer. The query and its answer go through
+// /dev/tty, the controlling terminal of the process, rather than through
+// out. The terminal settings are restored before DetectTheme returns, so an
+// answer arriving later is left in the input of the terminal.
+func DetectTheme(out io.Writer) ThemeDef {
+	if isLightBackground(out) {
+		return NewLightTheme()
+	}
+	return NewDefaultTheme()
+}
+
+// isLightBackground reports whether out is a terminal with a light background.
+func isLightBackground(out io.Writer) bool {
+	if light, ok := parseColorFGBG(os.Getenv("COLORFGBG")); ok {
+		return light
+	}
+	f, ok := out.(*os.File)
+	if !ok || !isTerminal(f.Fd()) {
+		return false
+	}
+	tty, err := openTTY()
+	if err != nil {
+		return false
+	}
+	r, g, b, ok := queryBackground(tty, backgroundQueryTimeout)
+	return ok && isLight(r, g, b)
+}
+
+// parseColorFGBG parses the value of the COLORFGBG environment variable,
+// like "15;0" or "0;default;15", set by some terminals to the indexes of
+// their foreground and background colors. It reports whether the background
+// is light, and whether it is known.
+func parseColorFGBG(s string) (light, ok bool) {
+	if s == "" {
+		return false, false
+	}
+	bg, err := strconv.Atoi(s[strings.LastIndexByte(s, ';')+1:])
+	if err != nil || bg < 0 || bg > 15 {
+		return false, false
+	}
+	// Basic colors 0 to 6 and 8 are dark ones.
+	return bg == 7 || bg > 8, true
+}
+
+// isLight reports whether a color is light, from its perceived brightness.
+func isLight(r, g, b uint8) bool {
+	return 299*int(r)+587*int(g)+114*int(b) > 1000*255/2
+}
+
+// queryBackground queries the terminal tty for its background color with an
+// OSC 11 escape sequence. A primary device attributes query (DA1), which
+// all terminals answer, follows it so that the answer of terminals not
+// supporting OSC 11 isn't waited for until the timeout.
+// The answers are read until the DA1 one, which comes last, or the timeout.
+// The settings of tty are restored, and tty closed, before returning.
+func queryBackground(tty *os.File, timeout time.Duration) (r, g, b uint8, ok bool) {
+	defer tty.Close()
+	restore, ok := setReadTimeout(tty.Fd())
+	if !ok {
+		return 0, 0, 0, false
+	}
+	defer restore()
+	if _, err := tty.WriteString("\x1b]11;?\x1b\\\x1b[c"); err != nil {
+		return 0, 0, 0, false
+	}
+	var resp []byte
+	buf := make([]byte, 64)
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		n, err := tty.Read(buf)
+		resp = append(resp, buf[:n]...)
+		if hasDA1Answer(resp) {
+			break
+		}
+		if err != nil && err != io.EOF {
+			break
+		}
+	}
+	return parseOSC11Answer(resp)
+}
+
+// hasDA1Answer reports whether resp holds an answer to a DA1 query,
+// like "\x1b[?62;22c".
+func hasDA1Answer(resp []byte) bool {
+	i := bytes.Index(resp, []byte("\x1b[?"))
+	return i >= 0 && bytes.IndexByte(resp[i:], 'c') >= 0
+}
+
+// parseOSC11Answer parses the color reported in an answer to an OSC 11
+// query, like "\x1b]11;rgb:ffff/ffff/ffff\x1b\\".
+func parseOSC11Answer(resp []byte) (r, g, b uint8, ok bool) {
+	i := bytes.Index(resp, []byte("\x1b]11;rgb:"))
+	if i < 0 {
+		return 0, 0, 0, false
+	}
+	spec := resp[i+len("\x1b]11;rgb:"):]
+	if end := bytes.IndexAny(spec, "\x07\x1b"); end >= 0 {
+		spec = spec[:end]
+	} else {
+		return 0, 0, 0, false
+	}
+	parts := strings.Split(string(spec), "/")
+	if len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+	var c [3]uint8
+	for i, p := range parts {
+		if len(p) == 0 || len(p) > 4 {
+			return 0, 0, 0, false
+		}
+		v, err := strconv.ParseUint(p, 16, 16)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		// Scale the value from 1 to 4 hex digits to 8 bits
+		c[i] = uint8(v * 255 / (1<<(4*len(p)) - 1))
+	}
+	return c[0], c[1], c[2], true
+}
@@ -0,0 +1,125 @@
+package console
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseColorFGBG(t *testing.T) {
+	for s, expected := range map[string]struct{ light, ok bool }{
+		"":               {false, false},
+		"15;0":           {false, true},
+		"0;15":           {true, true},
+		"0;7":            {true, true},
+		"7;8":            {false, true},
+		"12;default;0":   {false, true},
+		"0;default;15":   {true, true},
+		"15;default":     {false, false},
+		"0;16":           {false, false},
+		"not a color!;x": {false, false},
+	} {
+		light, ok := parseColorFGBG(s)
+		AssertEqual(t, expected.light, light)
+		AssertEqual(t, expected.ok, ok)
+	}
+}
+
+func TestParseOSC11Answer(t *testing.T) {
+	for s, expected := range map[string][3]uint8{
+		"\x1b]11;rgb:ffff/ffff/ffff\x1b\\":           {255, 255, 255},
+		"\x1b]11;rgb:2e2e/3434/4040\x07":             {46, 52, 64},
+		"\x1b]11;rgb:2e/34/40\x07":                   {46, 52, 64},
+		"\x1b]11;rgb:f/0/8\x07":                      {255, 0, 136},
+		"\x1b]11;rgb:fdfd/f6f6/e3e3\x1b\\\x1b[?1;2c": {253, 246, 227},
+	} {
+		r, g, b, ok := parseOSC11Answer([]byte(s))
+		AssertEqual(t, true, ok)
+		AssertEqual(t, expected, [3]uint8{r, g, b})
+	}
+	for _, s := range []string{"", "\x1b[?1;2c", "\x1b]11;rgb:ffff/ffff\x07", "\x1b]11;rgb:ffff/ffff/ffff", "\x1b]11;rgb:fffff/0/0\x07", "\x1b]11;rgb:zz/0/0\x07", "\x1b]11;rgb://0\x07"} {
+		_, _, _, ok := parseOSC11Answer([]byte(s))
+		AssertEqual(t, false, ok)
+	}
+	AssertEqual(t, true, isLight(253, 246, 227))
+	AssertEqual(t, false, isLight(0, 43, 54))
+}
+
+func TestDetectTheme(t *testing.T) {
+	t.Setenv("COLORFGBG", "0;15")
+	AssertEqual(t, "Light", DetectTheme(&bytes.Buffer{}).Name())
+	t.Setenv("COLORFGBG", "15;0")
+	AssertEqual(t, "Default", DetectTheme(&bytes.Buffer{}).Name())
+	os.Unsetenv("COLORFGBG")
+	AssertEqual(t, "Default", DetectTheme(&bytes.Buffer{}).Name())
+
+	master, slave := openPTY(t)
+	if master == nil {
+		t.Skip("no pseudo-terminal available")
+	}
+	ttyPath = slave.Name()
+	defer func() { ttyPath = "/dev/tty" }()
+	// answer emulates a terminal answering queries with the given string.
+	answer := func(s string) {
+		go func() {
+			buf := make([]byte, 64)
+			var query []byte
+			for !bytes.HasSuffix(query, []byte("\x1b[c")) {
+				n, err := master.Read(buf)
+				if err != nil {
+					return
+				}
+				query = append(query, buf[:n]...)
+			}
+			AssertEqual(t, "\x1b]11;?\x1b\\\x1b[c", string(query))
+			master.WriteString(s)
+		}()
+	}
+
+	answer("\x1b]11;rgb:fdfd/f6f6/e3e3\x1b\\\x1b[?62;22c")
+	AssertEqual(t, "Light", DetectTheme(slave).Name())
+
+	answer("\x1b]11;rgb:0000/2b2b/3636\x07\x1b[?62;22c")
+	AssertEqual(t, "Default", DetectTheme(slave).Name())
+
+	// Terminals not supporting OSC 11 only answer the DA1 query
+	answer("\x1b[?62;22c")
+	start := time.Now()
+	AssertEqual(t, "Default", DetectTheme(slave).Name())
+	if d := time.Since(start); d >= backgroundQueryTimeout {
+		t.Errorf("DA1 answer not taken into account, detection took %s", d)
+	}
+
+	// Terminals not answering at all, whose settings are restored on return
+	before := termSettings(t, slave)
+	answer("")
+	start = time.Now()
+	AssertEqual(t, "Default", DetectTheme(slave).Name())
+	if d := time.Since(start); d > 5*backgroundQueryTimeout {
+		t.Errorf("detection took %s", d)
+	}
+	AssertEqual(t, before, termSettings(t, slave))
+
+	answer("\x1b]11;rgb:ffff/ffff/ffff\x07\x1b[?62;22c")
+	h := NewHandler(slave, &HandlerOptions{AutoTheme: true, ColorMode: ColorAlways})
+	AssertEqual(t, "Light", h.opts.Theme.Name())
+	h = NewHandler(slave, &HandlerOptions{AutoTheme: true, NoColor: true})
+	AssertEqual(t, "Default", h.opts.Theme.Name())
+}
+
+func TestSetReadTimeout(t *testing.T) {
+	_, slave := openPTY(t)
+	if slave == nil {
+		t.Skip("no pseudo-terminal available")
+	}
+	before := termSettings(t, slave)
+	restore, ok := setReadTimeout(slave.Fd())
+	AssertEqual(t, true, ok)
+	AssertNotEqual(t, before, termSettings(t, slave))
+	restore()
+	AssertEqual(t, before, termSettings(t, slave))
+
+	_, ok = setReadTimeout(^uintptr(0))
+	AssertEqual(t, false, ok)
+}
@@ -21,7 +21,19 @@ var handlers = []struct {
 	hdl  slog.Handler
 }{
 	{"dummy", &DummyHandler{}},
-	{"console", NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelDebug, AddSource: false})},
+	{"console", NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelDebug, AddSource: false, ColorMode: ColorAlways})},
+	{"console-styled", NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelDebug, AddSource: false, ColorMode: ColorAlways,
+		KeyStyles: []KeyStyle{
+			{Key: "test.int", Ranges: []ValueRange{{Min: 0, Max: 10, Style: ToANSICode(Green)}, {Min: 10, Max: 100, Style: ToANSICode(Red)}}},
+			{Key: "*.bar", KeyStyle: ToANSICode(Magenta)},
+			{Key: "dur", ValueStyle: ToANSICode(Yellow)},
+		},
+		HashKeys:  []string{"group.bar", "*.int"},
+		PrefixKey: "foo", PrefixWidth: 6, PrefixHash: true,
+		PrettyValues: true,
+		MaxValueLen:  64, KeyMaxValueLens: []KeyMaxLen{{Key: "group.*", MaxLen: 2}},
+		Redact: RedactOptions{Keys: []string{"password", "*.token"}, Detectors: []func(string) bool{DetectJWT, DetectBearerToken, DetectCardNumber}},
+	})},
 	{"std-text", slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false})},
 	{"std-json", slog.NewJSONHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: false})},
 }
@@ -71,9 +71,22 @@ func (b *buffer) AppendString(s string) {
 	*b = append(*b, s...)
 }
 
-// func (b *buffer) AppendQuotedString(s string) {
-// 	b.buff = strconv.AppendQuote(b.buff, s)
-// }
+func (b *buffer) AppendSanitizedString(s string) {
+	*b = appendSanitized(*b, s)
+}
+
+func (b *buffer) AppendQuotedString(s string) {
+	*b = strconv.AppendQuote(*b, s)
+}
+
+// AppendEscapedString appends s escaped like AppendQuotedString would,
+// but without the surrounding quotes.
+func (b *buffer) AppendEscapedString(s string) {
+	n := len(*b)
+	*b = strconv.AppendQuote(*b, s)
+	copy((*b)[n:], (*b)[n+1:len(*b)-1])
+	*b = (*b)[:len(*b)-2]
+}
 
 func (b *buffer) AppendByte(byt byte) {
 	*b = append(*b, byt)
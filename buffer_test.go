@@ -34,6 +34,16 @@ func TestBuffer_Append(t *testing.T) {
 	AssertEqual(t, "foobarbaz.truefalse3.144212foo1s"+now.Format(time.RFC3339), b.String())
 }
 
+func TestBuffer_AppendQuoted(t *testing.T) {
+	b := new(buffer)
+	b.AppendQuotedString("foo \"bar\"\n")
+	AssertEqual(t, `"foo \"bar\"\n"`, b.String())
+	b.Reset()
+	b.AppendString("key=")
+	b.AppendEscapedString("foo \"bar\"\n")
+	AssertEqual(t, `key=foo \"bar\"\n`, b.String())
+}
+
 func TestBuffer_WriteTo(t *testing.T) {
 	dest := bytes.Buffer{}
 	b := new(buffer)
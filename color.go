@@ -0,0 +1,110 @@
+package console
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// ColorMode defines when the handler emits ANSI color codes.
+type ColorMode int
+
+const (
+	// ColorAuto enables colors only if the output is a terminal.
+	// The NO_COLOR, CLICOLOR_FORCE, FORCE_COLOR, TERM and CLICOLOR
+	// environment variables are honoured, in this order of precedence.
+	ColorAuto ColorMode = iota
+	// ColorAlways always enables colors.
+	ColorAlways
+	// ColorNever always disables colors.
+	ColorNever
+)
+
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAuto:
+		return "auto"
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	default:
+		return "unknown"
+	}
+}
+
+// enabled reports whether colors must be written to out in mode m.
+func (m ColorMode) enabled(out io.Writer) bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	// See https://no-color.org/ and https://bixense.com/clicolors/
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v := os.Getenv("CLICOLOR_FORCE"); v != "" && v != "0" {
+		return true
+	}
+	if v := os.Getenv("FORCE_COLOR"); v != "" {
+		return v != "0" && v != "false"
+	}
+	if os.Getenv("TERM") == "dumb" || os.Getenv("CLICOLOR") == "0" {
+		return false
+	}
+	f, ok := out.(*os.File)
+	return ok && isTerminal(f.Fd())
+}
+
+// ColorDepth defines the number of colors the output supports.
+// Styles using more colors are downgraded to the nearest supported ones.
+type ColorDepth int
+
+const (
+	// ColorDepthAuto detects the color depth from the COLORTERM and TERM
+	// environment variables, defaulting to ColorDepth16.
+	ColorDepthAuto ColorDepth = iota
+	// ColorDepth16 supports the 16 basic colors.
+	ColorDepth16
+	// ColorDepth256 supports the 256 colors palette.
+	ColorDepth256
+	// ColorDepthTrueColor supports 24-bit RGB colors.
+	ColorDepthTrueColor
+)
+
+func (d ColorDepth) String() string {
+	switch d {
+	case ColorDepthAuto:
+		return "auto"
+	case ColorDepth16:
+		return "16"
+	case ColorDepth256:
+		return "256"
+	case ColorDepthTrueColor:
+		return "truecolor"
+	default:
+		return "unknown"
+	}
+}
+
+// resolve returns d, or the color depth detected from the environment if d
+// is ColorDepthAuto.
+func (d ColorDepth) resolve() ColorDepth {
+	if d != ColorDepthAuto {
+		return d
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorDepthTrueColor
+	}
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case strings.Contains(term, "truecolor"), strings.Contains(term, "24bit"), strings.HasSuffix(term, "-direct"):
+		return ColorDepthTrueColor
+	case strings.Contains(term, "256color"):
+		return ColorDepth256
+	}
+	return ColorDepth16
+}
@@ -0,0 +1,90 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openTerminal opens the master side of a new pseudo-terminal,
+// or returns nil if the platform does not provide one.
+func openTerminal(t *testing.T) *os.File {
+	t.Helper()
+	f, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestColorMode_Enabled(t *testing.T) {
+	for _, env := range []string{"NO_COLOR", "CLICOLOR_FORCE", "FORCE_COLOR", "TERM", "CLICOLOR"} {
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+	tty := openTerminal(t)
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+	AssertNoError(t, err)
+	defer file.Close()
+
+	tests := []struct {
+		name     string
+		mode     ColorMode
+		env      map[string]string
+		tty      bool
+		expected bool
+	}{
+		{name: "always", mode: ColorAlways, expected: true},
+		{name: "always with NO_COLOR", mode: ColorAlways, env: map[string]string{"NO_COLOR": "1"}, expected: true},
+		{name: "never", mode: ColorNever, tty: true, expected: false},
+		{name: "auto", mode: ColorAuto, expected: false},
+		{name: "auto terminal", mode: ColorAuto, tty: true, expected: true},
+		{name: "NO_COLOR", mode: ColorAuto, env: map[string]string{"NO_COLOR": "1"}, tty: true, expected: false},
+		{name: "NO_COLOR empty", mode: ColorAuto, env: map[string]string{"NO_COLOR": ""}, tty: true, expected: true},
+		{name: "NO_COLOR and FORCE_COLOR", mode: ColorAuto, env: map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "1"}, expected: false},
+		{name: "FORCE_COLOR", mode: ColorAuto, env: map[string]string{"FORCE_COLOR": "1"}, expected: true},
+		{name: "FORCE_COLOR empty", mode: ColorAuto, env: map[string]string{"FORCE_COLOR": ""}, expected: false},
+		{name: "FORCE_COLOR empty on terminal", mode: ColorAuto, env: map[string]string{"FORCE_COLOR": ""}, tty: true, expected: true},
+		{name: "FORCE_COLOR=0", mode: ColorAuto, env: map[string]string{"FORCE_COLOR": "0"}, tty: true, expected: false},
+		{name: "CLICOLOR_FORCE", mode: ColorAuto, env: map[string]string{"CLICOLOR_FORCE": "1"}, expected: true},
+		{name: "CLICOLOR_FORCE=0", mode: ColorAuto, env: map[string]string{"CLICOLOR_FORCE": "0"}, expected: false},
+		{name: "CLICOLOR=0", mode: ColorAuto, env: map[string]string{"CLICOLOR": "0"}, tty: true, expected: false},
+		{name: "TERM=dumb", mode: ColorAuto, env: map[string]string{"TERM": "dumb"}, tty: true, expected: false},
+		{name: "TERM=dumb and CLICOLOR_FORCE", mode: ColorAuto, env: map[string]string{"TERM": "dumb", "CLICOLOR_FORCE": "1"}, expected: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			for _, out := range []io.Writer{&bytes.Buffer{}, file} {
+				AssertEqual(t, tc.expected && !tc.tty, tc.mode.enabled(out))
+			}
+			if tc.tty {
+				if tty == nil {
+					t.Skip("no terminal available")
+				}
+				AssertEqual(t, tc.expected, tc.mode.enabled(tty))
+			}
+		})
+	}
+}
+
+func TestHandler_ColorModeAuto(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "1")
+	h := NewHandler(&bytes.Buffer{}, nil)
+	AssertEqual(t, false, h.opts.NoColor)
+	h = NewHandler(&bytes.Buffer{}, &HandlerOptions{NoColor: true, ColorMode: ColorAlways})
+	AssertEqual(t, true, h.opts.NoColor)
+
+	t.Setenv("FORCE_COLOR", "0")
+	h = NewHandler(&bytes.Buffer{}, nil)
+	AssertEqual(t, true, h.opts.NoColor)
+	h = NewHandler(&bytes.Buffer{}, &HandlerOptions{ColorMode: ColorAlways})
+	AssertEqual(t, false, h.opts.NoColor)
+}
@@ -1,22 +1,93 @@
 package console
 
 import (
+	"bytes"
 	"fmt"
 	"log/slog"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+// blockGutter prefixes the continuation lines written below a record.
+const blockGutter = "│"
+
 type encoder struct {
-	opts HandlerOptions
+	opts        HandlerOptions
+	levels      levelLabels
+	depth       ColorDepth
+	theme       *ThemeDef // styles of the Theme option, downgraded to depth
+	hashPalette []ANSIMod
+	layout      layout
+	// hasKeyStyles is set if the KeyStyles or HashKeys options are,
+	// so that keys are only matched against patterns if needed.
+	hasKeyStyles bool
+	// hasRedact is set if the Redact option has keys or detectors.
+	hasRedact bool
+	// hasTruncate is set if the MaxValueLen or KeyMaxValueLens options are.
+	hasTruncate bool
+	// levelTheme is the Theme option if it styles levels itself,
+	// and isn't a ThemeDef.
+	levelTheme interface{ Level(slog.Level) ANSIMod }
+}
+
+func newEncoder(opts HandlerOptions) *encoder {
+	e := &encoder{opts: opts, levels: newLevelLabels(opts.LevelNames), depth: opts.ColorDepth.resolve()}
+	e.opts.KeyStyles = cloneKeyStyles(opts.KeyStyles, e.depth)
+	e.hasKeyStyles = len(opts.KeyStyles) > 0 || len(opts.HashKeys) > 0
+	e.opts.Redact.Keys = cloneRedactKeys(opts.Redact.Keys)
+	e.opts.Redact.Detectors = slices.Clone(opts.Redact.Detectors)
+	e.hasRedact = len(opts.Redact.Keys) > 0 || len(opts.Redact.Detectors) > 0
+	e.opts.KeyMaxValueLens = slices.Clone(opts.KeyMaxValueLens)
+	e.hasTruncate = opts.MaxValueLen > 0 || len(opts.KeyMaxValueLens) > 0
+	theme := NewTheme(opts.Theme)
+	for _, el := range []Element{ElementAttrValueString, ElementAttrValueNumber, ElementAttrValueBool,
+		ElementAttrValueTime, ElementAttrValueDuration, ElementAttrValueNil} {
+		if theme.styles[el] == "" {
+			theme.styles[el] = theme.styles[ElementAttrValue]
+		}
+	}
+	for _, el := range []Element{ElementErrorTree, ElementStackFrameStd} {
+		if theme.styles[el] == "" {
+			theme.styles[el] = theme.styles[ElementTimestamp]
+		}
+	}
+	for el, style := range theme.styles {
+		theme.styles[el] = downgradeStyle(style, e.depth)
+	}
+	theme.levels = slices.Clone(theme.levels)
+	for i := range theme.levels {
+		theme.levels[i].style = downgradeStyle(theme.levels[i].style, e.depth)
+	}
+	e.theme = &theme
+	if _, ok := opts.Theme.(ThemeDef); !ok {
+		e.levelTheme, _ = opts.Theme.(interface{ Level(slog.Level) ANSIMod })
+	}
+	e.hashPalette = e.newHashPalette()
+	return e
+}
+
+// style returns the style of the element el.
+func (e *encoder) style(el Element) ANSIMod {
+	return e.theme.styles[el]
 }
 
-func (e encoder) NewLine(buf *buffer) {
+// levelStyle returns the style of the level l.
+func (e *encoder) levelStyle(l slog.Level) ANSIMod {
+	if e.levelTheme != nil {
+		return downgradeStyle(e.levelTheme.Level(l), e.depth)
+	}
+	return e.theme.Level(l)
+}
+
+func (e *encoder) NewLine(buf *buffer) {
 	buf.AppendByte('\n')
 }
 
-func (e encoder) withColor(b *buffer, c ANSIMod, f func()) {
+func (e *encoder) withColor(b *buffer, c ANSIMod, f func()) {
 	if c == "" || e.opts.NoColor {
 		f()
 		return
@@ -26,108 +97,394 @@ func (e encoder) withColor(b *buffer, c ANSIMod, f func()) {
 	b.AppendString(string(ResetMod))
 }
 
-func (e encoder) writeColoredTime(w *buffer, t time.Time, format string, c ANSIMod) {
+func (e *encoder) writeColoredTime(w *buffer, t time.Time, format string, c ANSIMod) {
 	e.withColor(w, c, func() {
 		w.AppendTime(t, format)
 	})
 }
 
-func (e encoder) writeColoredString(w *buffer, s string, c ANSIMod) {
+func (e *encoder) writeColoredString(w *buffer, s string, c ANSIMod) {
 	e.withColor(w, c, func() {
 		w.AppendString(s)
 	})
 }
 
-func (e encoder) writeColoredInt(w *buffer, i int64, c ANSIMod) {
+// appendSanitized appends the untrusted string s, escaping
+// control characters unless the NoSanitize option is set.
+func (e *encoder) appendSanitized(w *buffer, s string) {
+	if e.opts.NoSanitize {
+		w.AppendString(s)
+	} else {
+		w.AppendSanitizedString(s)
+	}
+}
+
+// writeColoredSanitizedString writes the untrusted string s,
+// escaping control characters unless the NoSanitize option is set.
+func (e *encoder) writeColoredSanitizedString(w *buffer, s string, c ANSIMod) {
+	e.withColor(w, c, func() {
+		e.appendSanitized(w, s)
+	})
+}
+
+// writeColoredQuotedString writes s, quoted according to the QuoteMode option.
+// Quoting escapes control characters, otherwise s is sanitized.
+func (e *encoder) writeColoredQuotedString(w *buffer, s string, c ANSIMod) {
+	e.withColor(w, c, func() {
+		switch e.opts.QuoteMode {
+		case QuoteAlways:
+			w.AppendQuotedString(s)
+		case QuoteAuto:
+			if needsQuoting(s) {
+				w.AppendQuotedString(s)
+			} else {
+				// s has no control characters, no need to sanitize it.
+				w.AppendString(s)
+			}
+		default:
+			e.appendSanitized(w, s)
+		}
+	})
+}
+
+// writeColoredQuotedTime writes t, quoted according to the QuoteMode option.
+func (e *encoder) writeColoredQuotedTime(w *buffer, t time.Time, format string, c ANSIMod) {
+	e.withColor(w, c, func() {
+		start := w.Len()
+		w.AppendTime(t, format)
+		switch {
+		case e.opts.QuoteMode == QuoteAlways,
+			e.opts.QuoteMode == QuoteAuto && (start == w.Len() || bytes.ContainsAny((*w)[start:], " =\"")):
+			// Time layouts can't contain characters needing to be escaped,
+			// so surrounding the formatted time with quotes is enough.
+			w.AppendByte('"')
+			copy((*w)[start+1:], (*w)[start:])
+			(*w)[start] = '"'
+			w.AppendByte('"')
+		}
+	})
+}
+
+func (e *encoder) writeColoredInt(w *buffer, i int64, c ANSIMod) {
 	e.withColor(w, c, func() {
 		w.AppendInt(i)
 	})
 }
 
-func (e encoder) writeColoredUint(w *buffer, i uint64, c ANSIMod) {
+func (e *encoder) writeColoredUint(w *buffer, i uint64, c ANSIMod) {
 	e.withColor(w, c, func() {
 		w.AppendUint(i)
 	})
 }
 
-func (e encoder) writeColoredFloat(w *buffer, i float64, c ANSIMod) {
+func (e *encoder) writeColoredFloat(w *buffer, i float64, c ANSIMod) {
 	e.withColor(w, c, func() {
 		w.AppendFloat(i)
 	})
 }
 
-func (e encoder) writeColoredBool(w *buffer, b bool, c ANSIMod) {
+func (e *encoder) writeColoredBool(w *buffer, b bool, c ANSIMod) {
 	e.withColor(w, c, func() {
 		w.AppendBool(b)
 	})
 }
 
-func (e encoder) writeColoredDuration(w *buffer, d time.Duration, c ANSIMod) {
+func (e *encoder) writeColoredDuration(w *buffer, d time.Duration, c ANSIMod) {
 	e.withColor(w, c, func() {
 		w.AppendDuration(d)
 	})
 }
 
-func (e encoder) writeTimestamp(buf *buffer, tt time.Time) {
-	if !tt.IsZero() {
-		e.writeColoredTime(buf, tt, e.opts.TimeFormat, e.opts.Theme.Timestamp())
-		buf.AppendByte(' ')
+// replaceBuiltin calls the ReplaceAttr option, if any, on the built-in
+// attribute with the given key and value. It returns the value to render
+// and reports whether the attribute must be rendered at all.
+func (e *encoder) replaceBuiltin(key string, value slog.Value) (slog.Value, bool) {
+	a := e.opts.ReplaceAttr(nil, slog.Attr{Key: key, Value: value})
+	if a.Key == "" {
+		return slog.Value{}, false
 	}
+	return resolve(a.Value), true
 }
 
-func (e encoder) writeSource(buf *buffer, pc uintptr, cwd string) {
+func (e *encoder) writeTimestamp(buf *buffer, tt time.Time) bool {
+	if tt.IsZero() {
+		return false
+	}
+	if e.opts.ReplaceAttr != nil {
+		value, ok := e.replaceBuiltin(slog.TimeKey, slog.TimeValue(tt))
+		if !ok {
+			return false
+		}
+		if value.Kind() != slog.KindTime {
+			e.writeColoredSanitizedString(buf, value.String(), e.style(ElementTimestamp))
+			return true
+		}
+		tt = value.Time()
+	}
+	e.writeColoredTime(buf, tt, e.opts.TimeFormat, e.style(ElementTimestamp))
+	return true
+}
+
+func (e *encoder) writeSource(buf *buffer, pc uintptr, cwd string) bool {
 	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if e.opts.ReplaceAttr != nil {
+		value, ok := e.replaceBuiltin(slog.SourceKey, slog.AnyValue(&slog.Source{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		}))
+		if !ok {
+			return false
+		}
+		src, isSource := value.Any().(*slog.Source)
+		if value.Kind() != slog.KindAny || !isSource || src == nil {
+			e.writeColoredSanitizedString(buf, value.String(), e.style(ElementSource))
+			return true
+		}
+		frame.File, frame.Line = src.File, src.Line
+	}
 	if cwd != "" {
 		if ff, err := filepath.Rel(cwd, frame.File); err == nil {
 			frame.File = ff
 		}
 	}
-	e.withColor(buf, e.opts.Theme.Source(), func() {
-		buf.AppendString(frame.File)
+	e.withColor(buf, e.style(ElementSource), func() {
+		e.appendSanitized(buf, frame.File)
 		buf.AppendByte(':')
 		buf.AppendInt(int64(frame.Line))
 	})
-	e.writeColoredString(buf, " > ", e.opts.Theme.AttrKey())
+	return true
 }
 
-func (e encoder) writeMessage(buf *buffer, level slog.Level, msg string) {
-	if level >= slog.LevelInfo {
-		e.writeColoredString(buf, msg, e.opts.Theme.Message())
+func (e *encoder) writeMessage(buf, block *buffer, level slog.Level, msg string) bool {
+	if e.opts.ReplaceAttr != nil {
+		value, ok := e.replaceBuiltin(slog.MessageKey, slog.StringValue(msg))
+		if !ok {
+			return false
+		}
+		msg = value.String()
+	}
+	style := e.style(ElementMessage)
+	if level < slog.LevelInfo {
+		style = e.style(ElementMessageDebug)
+	}
+	msg, omitted := truncate(msg, e.opts.MaxMessageLen)
+	n := block.Len()
+	if e.opts.Multiline {
+		if first, rest, ok := strings.Cut(msg, "\n"); ok {
+			msg = strings.TrimSuffix(first, "\r")
+			e.writeBlockLines(block, rest, "", style)
+		}
+	}
+	e.writeColoredSanitizedString(buf, msg, style)
+	// The truncation mark ends the last line of the message.
+	if block.Len() > n {
+		e.writeBlockTruncationMark(block, omitted)
 	} else {
-		e.writeColoredString(buf, msg, e.opts.Theme.MessageDebug())
+		e.writeTruncationMark(buf, omitted)
 	}
+	return true
+}
+
+// writeBlockKey writes the key on its own line in the block,
+// so that the value can be written indented below.
+func (e *encoder) writeBlockKey(block *buffer, key string, groups []string) {
+	e.withColor(block, e.keyRule(key, groups).keyStyle(e.style(ElementAttrKey)), func() {
+		e.writeKey(block, key, groups)
+		block.AppendByte('=')
+	})
+	block.AppendByte('\n')
 }
 
-func (e encoder) writeAttr(buf *buffer, a slog.Attr, group string) {
+// appendKeyPart appends a key or group name which doesn't need quoting.
+func (e *encoder) appendKeyPart(buf *buffer, s string) {
+	if e.opts.QuoteMode == QuoteNever {
+		e.appendSanitized(buf, s)
+	} else {
+		// needsQuoting(s) is false so s has no control characters.
+		buf.AppendString(s)
+	}
+}
+
+// writeBlockLines writes each line of s on its own line of block, prefixed with indent.
+// A trailing newline in s does not produce an empty line.
+func (e *encoder) writeBlockLines(block *buffer, s string, indent string, c ANSIMod) {
+	for s != "" {
+		line, rest, _ := strings.Cut(s, "\n")
+		block.AppendString(indent)
+		e.writeColoredSanitizedString(block, strings.TrimSuffix(line, "\r"), c)
+		block.AppendByte('\n')
+		s = rest
+	}
+}
+
+// writeBlock writes the lines of block below the record header, each of them
+// being indented and prefixed with a gutter colored after the record's level.
+func (e *encoder) writeBlock(buf *buffer, level slog.Level, block *buffer) {
+	lines := block.Bytes()
+	for len(lines) > 0 {
+		i := bytes.IndexByte(lines, '\n') + 1
+		buf.AppendString("  ")
+		e.writeColoredString(buf, blockGutter, e.levelStyle(level))
+		buf.AppendByte(' ')
+		buf.Append(lines[:i])
+		lines = lines[i:]
+	}
+}
+
+func (e *encoder) writeAttr(buf, block *buffer, a slog.Attr, groups []string) {
+	if e.opts.PrefixKey != "" && e.isPrefixKey(a.Key, groups) {
+		// Written in the prefix column instead.
+		return
+	}
+	a.Value = resolve(a.Value)
+	if rep := e.opts.ReplaceAttr; rep != nil && a.Value.Kind() != slog.KindGroup {
+		// groups is cloned because it may point to a stack allocated array,
+		// which would otherwise escape to the heap on every record.
+		a = rep(slices.Clone(groups), a)
+		a.Value = resolve(a.Value)
+	}
 	// Elide empty Attrs.
 	if a.Equal(slog.Attr{}) {
 		return
 	}
-	value := a.Value.Resolve()
+	value := a.Value
+	if e.hasRedact && e.redactKey(a.Key, groups) {
+		e.writeRedacted(buf, a.Key, groups, value)
+		return
+	}
 	if value.Kind() == slog.KindGroup {
-		subgroup := a.Key
-		if group != "" {
-			subgroup = group + "." + a.Key
+		if a.Key != "" {
+			groups = append(groups, a.Key)
 		}
 		for _, attr := range value.Group() {
-			e.writeAttr(buf, attr, subgroup)
+			e.writeAttr(buf, block, attr, groups)
 		}
 		return
 	}
-	buf.AppendByte(' ')
-	e.withColor(buf, e.opts.Theme.AttrKey(), func() {
-		if group != "" {
-			buf.AppendString(group)
-			buf.AppendByte('.')
+	if len(e.opts.Redact.Detectors) > 0 && !isScalar(value.Kind()) {
+		if s, _, _ := e.textValue(value); e.redactValue(s) {
+			e.writeRedacted(buf, a.Key, groups, value)
+			return
 		}
-		buf.AppendString(a.Key)
+	}
+	if err, ok := anyValue(value).(error); ok {
+		e.writeErrorAttr(buf, block, a.Key, groups, err, value)
+		return
+	}
+	e.writeKeyValue(buf, block, a.Key, groups, value)
+}
+
+// writeKeyValue writes a non-group attribute in buf, or in block
+// if its value spans multiple lines.
+func (e *encoder) writeKeyValue(buf, block *buffer, key string, groups []string, value slog.Value) {
+	if v := anyValue(value); e.opts.PrettyValues && isPretty(v) {
+		e.writePrettyKeyValue(buf, block, key, groups, v)
+		return
+	}
+	rule, hash := e.matchKey(key, groups)
+	var s string
+	var style ANSIMod
+	isText := !isScalar(value.Kind())
+	if isText {
+		s, style, _ = e.textValue(value)
+	} else {
+		style = e.kindStyle(value.Kind())
+	}
+	if hash {
+		style = e.hashStyle(value, s, isText)
+	}
+	style = rule.valueStyle(value, style)
+	omitted := 0
+	if isText && e.hasTruncate {
+		s, omitted = truncate(s, e.maxValueLen(key, groups))
+	}
+	if isText && e.opts.Multiline && strings.IndexByte(s, '\n') >= 0 {
+		e.writeBlockKey(block, key, groups)
+		e.writeBlockLines(block, s, "  ", style)
+		e.writeBlockTruncationMark(block, omitted)
+		return
+	}
+	buf.AppendByte(' ')
+	e.withColor(buf, rule.keyStyle(e.style(ElementAttrKey)), func() {
+		e.writeKey(buf, key, groups)
 		buf.AppendByte('=')
 	})
-	e.writeValue(buf, value)
+	if isText {
+		e.writeColoredQuotedString(buf, s, style)
+		e.writeTruncationMark(buf, omitted)
+	} else {
+		e.writeValue(buf, value, style)
+	}
+}
+
+// writeErrorAttr writes an attribute whose value is an error, expanding it
+// and its stack trace in block according to the options.
+func (e *encoder) writeErrorAttr(buf, block *buffer, key string, groups []string, err error, value slog.Value) {
+	// The error's methods are user code which may panic, in which case the
+	// error is rendered in buf, where panics are handled.
+	expanded := e.opts.ExpandErrors && tryWrite(block, func() bool {
+		if !isWrapping(err) {
+			return false
+		}
+		e.writeBlockKey(block, key, groups)
+		e.writeErrorTree(block, err)
+		return true
+	})
+	if !expanded {
+		e.writeKeyValue(buf, block, key, groups, value)
+	}
+	if e.opts.ErrorStackTraces {
+		tryWrite(block, func() bool {
+			st := innermostStackTrace(err)
+			if st.empty() {
+				return false
+			}
+			e.withColor(block, e.keyRule(key, groups).keyStyle(e.style(ElementAttrKey)), func() {
+				e.writeKey(block, key, groups)
+				block.AppendString(" stack trace:")
+			})
+			block.AppendByte('\n')
+			e.writeStackTrace(block, st, cwd)
+			return true
+		})
+	}
+}
+
+// writeCallerStackTrace writes the call stack captured for a record in block.
+func (e *encoder) writeCallerStackTrace(block *buffer, st stackTrace) {
+	e.writeColoredString(block, "stack trace:", e.style(ElementAttrKey))
+	block.AppendByte('\n')
+	e.writeStackTrace(block, st, cwd)
 }
 
-func (e encoder) writeValue(buf *buffer, value slog.Value) {
-	attrValue := e.opts.Theme.AttrValue()
+// writeKey writes the key prefixed with the groups, separated by dots.
+// Unless QuoteMode is QuoteNever, the whole dotted key is quoted if any part of it needs to.
+func (e *encoder) writeKey(buf *buffer, key string, groups []string) {
+	quote := false
+	if e.opts.QuoteMode != QuoteNever {
+		quote = needsQuoting(key)
+		for _, g := range groups {
+			quote = quote || needsQuoting(g)
+		}
+	}
+	if !quote {
+		for _, g := range groups {
+			e.appendKeyPart(buf, g)
+			buf.AppendByte('.')
+		}
+		e.appendKeyPart(buf, key)
+		return
+	}
+	buf.AppendByte('"')
+	for _, g := range groups {
+		buf.AppendEscapedString(g)
+		buf.AppendByte('.')
+	}
+	buf.AppendEscapedString(key)
+	buf.AppendByte('"')
+}
+func (e *encoder) writeValue(buf *buffer, value slog.Value, attrValue ANSIMod) {
 	switch value.Kind() {
 	case slog.KindInt64:
 		e.writeColoredInt(buf, value.Int64(), attrValue)
@@ -136,57 +493,111 @@ func (e encoder) writeValue(buf *buffer, value slog.Value) {
 	case slog.KindFloat64:
 		e.writeColoredFloat(buf, value.Float64(), attrValue)
 	case slog.KindTime:
-		e.writeColoredTime(buf, value.Time(), e.opts.TimeFormat, attrValue)
+		e.writeColoredQuotedTime(buf, value.Time(), e.opts.TimeFormat, attrValue)
 	case slog.KindUint64:
 		e.writeColoredUint(buf, value.Uint64(), attrValue)
 	case slog.KindDuration:
 		e.writeColoredDuration(buf, value.Duration(), attrValue)
+	default:
+		s, _, _ := e.textValue(value)
+		e.writeColoredQuotedString(buf, s, attrValue)
+	}
+}
+
+// textValue returns the string representation of a value rendered as text,
+// along with the style to render it with.
+// It reports false for values of a scalar kind, like numbers or times.
+// Panics raised by the Error and String methods are recovered.
+func (e *encoder) textValue(value slog.Value) (s string, style ANSIMod, isText bool) {
+	if isScalar(value.Kind()) {
+		return "", "", false
+	}
+	switch value.Kind() {
+	case slog.KindString:
+		if s := value.String(); s != "" {
+			return s, e.style(ElementAttrValueString), true
+		}
+		return "", e.style(ElementAttrValueNil), true
 	case slog.KindAny:
+		// Set now, as results are not assigned when the methods below panic.
+		isText = true
 		switch v := value.Any().(type) {
+		case nil:
+			return value.String(), e.style(ElementAttrValueNil), true
 		case error:
-			e.writeColoredString(buf, v.Error(), e.opts.Theme.AttrValueError())
-			return
+			defer e.recoverText(v, &s, &style)
+			return v.Error(), e.style(ElementAttrValueError), true
 		case fmt.Stringer:
-			e.writeColoredString(buf, v.String(), attrValue)
-			return
+			defer e.recoverText(v, &s, &style)
+			return v.String(), e.style(ElementAttrValue), true
+		}
+		if isNilPointer(value.Any()) {
+			return value.String(), e.style(ElementAttrValueNil), true
 		}
-		fallthrough
-	case slog.KindString:
-		fallthrough
-	default:
-		e.writeColoredString(buf, value.String(), attrValue)
 	}
+	return value.String(), e.style(ElementAttrValue), true
 }
 
-func (e encoder) writeLevel(buf *buffer, l slog.Level) {
-	var style ANSIMod
-	var str string
-	var delta int
-	switch {
-	case l >= slog.LevelError:
-		style = e.opts.Theme.LevelError()
-		str = "ERR"
-		delta = int(l - slog.LevelError)
-	case l >= slog.LevelWarn:
-		style = e.opts.Theme.LevelWarn()
-		str = "WRN"
-		delta = int(l - slog.LevelWarn)
-	case l >= slog.LevelInfo:
-		style = e.opts.Theme.LevelInfo()
-		str = "INF"
-		delta = int(l - slog.LevelInfo)
-	case l >= slog.LevelDebug:
-		style = e.opts.Theme.LevelDebug()
-		str = "DBG"
-		delta = int(l - slog.LevelDebug)
-	default:
-		style = e.opts.Theme.LevelDebug()
-		str = "DBG"
-		delta = int(l - slog.LevelDebug)
+// isScalar reports whether values of the given kind are rendered as is,
+// rather than as text.
+func isScalar(kind slog.Kind) bool {
+	switch kind {
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64, slog.KindBool, slog.KindTime, slog.KindDuration:
+		return true
+	}
+	return false
+}
+
+// anyValue returns the value held by a value of kind slog.KindAny, or nil
+// for other kinds, whose Value.Any allocates.
+func anyValue(value slog.Value) any {
+	if value.Kind() != slog.KindAny {
+		return nil
 	}
-	if delta != 0 {
-		str = fmt.Sprintf("%s%+d", str, delta)
+	return value.Any()
+}
+
+// kindStyle returns the style of values of the given scalar kind.
+func (e *encoder) kindStyle(kind slog.Kind) ANSIMod {
+	switch kind {
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+		return e.style(ElementAttrValueNumber)
+	case slog.KindBool:
+		return e.style(ElementAttrValueBool)
+	case slog.KindTime:
+		return e.style(ElementAttrValueTime)
+	case slog.KindDuration:
+		return e.style(ElementAttrValueDuration)
 	}
-	e.writeColoredString(buf, str, style)
-	buf.AppendByte(' ')
+	return e.style(ElementAttrValue)
+}
+
+func (e *encoder) writeLevel(buf *buffer, l slog.Level) bool {
+	if e.opts.ReplaceAttr != nil {
+		value, ok := e.replaceBuiltin(slog.LevelKey, slog.AnyValue(l))
+		if !ok {
+			return false
+		}
+		lvl, isLevel := value.Any().(slog.Level)
+		if value.Kind() != slog.KindAny || !isLevel {
+			e.writeColoredSanitizedString(buf, value.String(), e.levelStyle(l))
+			return true
+		}
+		l = lvl
+	}
+	label, offset := e.levels.lookup(l)
+	e.withColor(buf, e.levelStyle(l), func() {
+		buf.AppendString(label)
+		if offset > 0 {
+			buf.AppendByte('+')
+		}
+		if offset != 0 {
+			buf.AppendInt(int64(offset))
+		}
+	})
+	// Pad labels to align messages
+	for n := utf8.RuneCountInString(label) + offsetWidth(offset); n < e.levels.width; n++ {
+		buf.AppendByte(' ')
+	}
+	return true
 }
@@ -0,0 +1,145 @@
+package console
+
+import (
+	"reflect"
+	"strings"
+)
+
+// maxErrorTreeDepth bounds the depth of rendered error trees,
+// protecting against cyclic or pathologically deep chains.
+const maxErrorTreeDepth = 32
+
+// isWrapping reports whether err wraps other errors.
+func isWrapping(err error) bool {
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		return x.Unwrap() != nil
+	case interface{ Unwrap() []error }:
+		return len(x.Unwrap()) > 0
+	}
+	return false
+}
+
+// splitError splits err into its own message and the errors it wraps,
+// either as a single error or as a list.
+// The wrapped errors' messages are trimmed from err's own message when
+// it was built by concatenating them, like fmt.Errorf and errors.Join do.
+func splitError(err error) (msg string, child error, children []error) {
+	msg = err.Error()
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		child = x.Unwrap()
+		if child != nil {
+			if cmsg := child.Error(); strings.HasSuffix(msg, cmsg) {
+				msg = strings.TrimRight(strings.TrimSuffix(msg, cmsg), ": ")
+			}
+		}
+	case interface{ Unwrap() []error }:
+		children = x.Unwrap()
+		if isJoined(msg, children) {
+			msg = ""
+		}
+	}
+	return msg, child, children
+}
+
+// isJoined reports whether msg is the concatenation of the messages
+// of errs separated by newlines, as produced by errors.Join.
+func isJoined(msg string, errs []error) bool {
+	first := true
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !first {
+			if !strings.HasPrefix(msg, "\n") {
+				return false
+			}
+			msg = msg[1:]
+		}
+		first = false
+		emsg := err.Error()
+		if !strings.HasPrefix(msg, emsg) {
+			return false
+		}
+		msg = msg[len(emsg):]
+	}
+	return msg == ""
+}
+
+// writeErrorTree writes err and the errors it wraps in block,
+// one per line and indented like a tree.
+func (e *encoder) writeErrorTree(block *buffer, err error) {
+	e.writeErrorNode(block, err, 0, 0, true)
+}
+
+// writeErrorNode writes the error at the given depth in the tree.
+// Bit i of open is set when the ancestor at depth i has siblings below it,
+// and last reports whether err is the last of its siblings.
+// Errors without their own message, like the ones returned by errors.Join,
+// are skipped and their children take their place.
+func (e *encoder) writeErrorNode(block *buffer, err error, depth int, open uint64, last bool) {
+	msg, child, children := splitError(err)
+	if depth >= maxErrorTreeDepth {
+		child, children = nil, nil
+	}
+	if msg == "" && (child != nil || len(children) > 0) {
+		if depth == 0 {
+			depth = 1
+		}
+		e.writeErrorChildren(block, child, children, depth, open, last)
+		return
+	}
+
+	treeStyle := e.style(ElementErrorTree)
+	block.AppendString("  ")
+	e.withColor(block, treeStyle, func() {
+		for i := 1; i < depth; i++ {
+			if open&(1<<i) != 0 {
+				block.AppendString("│  ")
+			} else {
+				block.AppendString("   ")
+			}
+		}
+		if depth > 0 {
+			if last {
+				block.AppendString("└─ ")
+			} else {
+				block.AppendString("├─ ")
+			}
+		}
+	})
+	e.writeColoredSanitizedString(block, msg, e.style(ElementAttrValueError))
+	if e.opts.ErrorTypes {
+		block.AppendByte(' ')
+		e.withColor(block, e.style(ElementSource), func() {
+			block.AppendByte('(')
+			block.AppendString(reflect.TypeOf(err).String())
+			block.AppendByte(')')
+		})
+	}
+	block.AppendByte('\n')
+
+	if depth > 0 && !last {
+		open |= 1 << depth
+	}
+	e.writeErrorChildren(block, child, children, depth+1, open, true)
+}
+
+// writeErrorChildren writes either child or children at the given depth,
+// last being the position of the group of children among its siblings.
+func (e *encoder) writeErrorChildren(block *buffer, child error, children []error, depth int, open uint64, last bool) {
+	if child != nil {
+		e.writeErrorNode(block, child, depth, open, last)
+		return
+	}
+	n := len(children)
+	for n > 0 && children[n-1] == nil {
+		n--
+	}
+	for i, c := range children[:n] {
+		if c != nil {
+			e.writeErrorNode(block, c, depth, open, last && i == n-1)
+		}
+	}
+}
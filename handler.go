@@ -30,22 +30,197 @@ type HandlerOptions struct {
 	// to adjust the minimum level dynamically, use a LevelVar.
 	Level slog.Leveler
 
-	// Disable colorized output
+	// Disable colorized output, regardless of ColorMode
 	NoColor bool
 
+	// ColorMode defines when colorized output is enabled.
+	// The zero value is ColorAuto, which enables colors only if out
+	// is a terminal and the environment does not disable them.
+	ColorMode ColorMode
+
+	// ColorDepth defines the number of colors the output supports.
+	// Theme styles using more colors are downgraded to the nearest supported ones.
+	// The zero value is ColorDepthAuto, which detects it from the COLORTERM
+	// and TERM environment variables.
+	ColorDepth ColorDepth
+
 	// TimeFormat is the format used for time.DateTime
 	TimeFormat string
 
-	// Theme defines the colorized output using ANSI escape sequences
+	// Theme defines the colorized output using ANSI escape sequences.
+	// If Theme is nil, the theme set by the CONSOLE_SLOG_THEME environment
+	// variable is used if valid, see ThemeFromEnv. Otherwise, including when
+	// the variable is invalid, the theme is detected with the AutoTheme
+	// option, or is the default one.
 	Theme Theme
+
+	// AutoTheme picks the Light or the Default theme according to the
+	// background color of the terminal, when Theme is nil, no theme is set
+	// by the environment and colors are enabled. See DetectTheme.
+	AutoTheme bool
+
+	// LevelNames defines the labels levels are printed with.
+	// Labels are padded to the width of the longest one.
+	// If LevelNames is nil, ShortLevelNames is used.
+	LevelNames LevelNames
+
+	// KeyStyles style the attributes whose key matches a pattern,
+	// overriding the theme. The first matching rule applies.
+	KeyStyles []KeyStyle
+
+	// HashKeys lists patterns, as in KeyStyle.Key, of the keys whose values
+	// are colored by hashing them into HashPalette, so that each distinct
+	// value, like a request ID, always gets the same color.
+	HashKeys []string
+
+	// HashPalette is the palette of HashKeys values.
+	// If empty, a palette of colors distinct from the ones the theme uses for
+	// levels is used.
+	HashPalette []ANSIMod
+
+	// PrefixKey is the key of an attribute, prefixed with its groups separated
+	// by dots, rendered between brackets before the message, like "[db]",
+	// instead of with the other attributes. The groups may be opened with
+	// WithGroup or be group attributes. It is taken from the record if
+	// present, or from the attributes added with WithAttrs otherwise.
+	PrefixKey string
+
+	// PrefixWidth is the width of the PrefixKey column. Shorter values are
+	// padded and longer ones truncated, and records without the attribute
+	// get a blank column. If zero, values are neither padded nor truncated.
+	PrefixWidth int
+
+	// PrefixHash colors PrefixKey values like the HashKeys ones,
+	// instead of with the theme's ElementPrefix style.
+	PrefixHash bool
+
+	// PrettyValues prints maps, slices, arrays and structs, possibly behind
+	// pointers, as colored JSON-like text, like {Name: "foo", Tags: ["a", "b"]},
+	// instead of with fmt's "%+v". Pointers are followed, errors and
+	// Stringers are printed as their text, and pointer cycles as "<cycle>".
+	// Fields and map entries are redacted if their names, prefixed with the
+	// attribute key, match Redact.Keys, but MaxValueLen, KeyMaxValueLens,
+	// the value styles of KeyStyles and HashKeys don't apply inside values.
+	PrettyValues bool
+
+	// PrettyMaxDepth is the nesting depth of containers up to which
+	// PrettyValues are printed. Pointers don't count. If zero, it is 5.
+	PrettyMaxDepth int
+
+	// PrettyMaxItems is the maximum number of elements, or fields, printed
+	// for each container of PrettyValues. If zero, it is 32.
+	PrettyMaxItems int
+
+	// PrettyExpanded prints PrettyValues on multiple indented lines,
+	// in the block of continuation lines below the record.
+	PrettyExpanded bool
+
+	// MaxValueLen is the maximum length, in characters, of textual attribute
+	// values (strings, errors, Stringers and other non scalar values).
+	// Longer values are truncated, and suffixed with the size of what was
+	// cut off, like "…(+12.3KB)". If zero, values are never truncated.
+	MaxValueLen int
+
+	// KeyMaxValueLens override MaxValueLen for the attributes whose key
+	// matches a pattern. The first matching rule applies.
+	KeyMaxValueLens []KeyMaxLen
+
+	// MaxMessageLen is the maximum length, in characters, of messages,
+	// truncated like values. If zero, messages are never truncated.
+	MaxMessageLen int
+
+	// Redact hides the values of sensitive attributes, like passwords or
+	// access tokens, including the ones added with WithAttrs, in groups and
+	// used as the prefix.
+	Redact RedactOptions
+
+	// Layout is a template of the records' first line, like
+	// "{time} {level} [{source}] {message} {attrs}". Its placeholders are
+	// {time}, {level}, {source}, {prefix} (see PrefixKey), {message} and
+	// {attrs}, each used at most once; "{{" and "}}" stand for literal braces.
+	// Elements left out are not rendered at all, not even in the block below
+	// records.
+	//
+	// The literal text around an element is omitted along with it when it is
+	// empty, like the source if AddSource is false: text directly before or
+	// after a placeholder sticks to it, and text separating elements, with
+	// whitespace at both ends, is written only between two elements.
+	//
+	// If empty, DefaultLayout is used. NewHandler panics if Layout is invalid,
+	// see ValidateLayout.
+	Layout string
+
+	// NoSanitize disables the escaping of control characters and other
+	// terminal escape sequences found in messages, keys and values.
+	// By default they are rendered escaped (e.g. "\x1b"), so that only
+	// the Theme's own codes can reach the terminal.
+	NoSanitize bool
+
+	// Multiline renders messages and text values containing newlines as an
+	// indented block of continuation lines below the record, prefixed with
+	// a gutter colored according to the record's level.
+	// By default, newlines are escaped and records always fit on one line.
+	Multiline bool
+
+	// ExpandErrors renders errors wrapping other errors, through
+	// Unwrap() error or Unwrap() []error (like errors.Join), as a tree in the
+	// block of continuation lines below the record, with one line per error.
+	ExpandErrors bool
+
+	// ErrorTypes adds the type name of each error to expanded errors.
+	ErrorTypes bool
+
+	// ErrorStackTraces prints the stack trace carried by errors in the block
+	// of continuation lines below the record. Errors carrying a stack trace
+	// implement one of:
+	//
+	//	Callers() []uintptr              // program counters, as from runtime.Callers
+	//	StackFrames() []runtime.Frame
+	//	StackTrace() T                   // T is a slice of program counters, like github.com/pkg/errors
+	//
+	// When errors are wrapped, the stack trace of the innermost one is printed.
+	ErrorStackTraces bool
+
+	// StackTraceLevel is the minimum level of the records for which the call
+	// stack is captured, starting at the log statement, and printed in the
+	// block of continuation lines below the record.
+	// If StackTraceLevel is nil, no call stack is captured.
+	StackTraceLevel slog.Leveler
+
+	// StackTraceMaxDepth is the maximum number of frames printed in stack traces.
+	// If zero, at most 32 frames are printed.
+	StackTraceMaxDepth int
+
+	// QuoteMode defines when attribute keys and values are quoted.
+	// The zero value is QuoteAuto.
+	QuoteMode QuoteMode
+
+	// ReplaceAttr is called to rewrite each non-group attribute before it is logged.
+	// The attribute's value has been resolved (see [slog.Value.Resolve]).
+	// If ReplaceAttr returns a zero Attr, the attribute is discarded.
+	//
+	// The built-in attributes with keys "time", "level", "source", and "msg"
+	// are passed to this function with an empty groups list. Their keys are not
+	// rendered, but returning an attribute with an empty key discards them.
+	// The "source" value is a *slog.Source, which is printed relative to the
+	// current working directory if it is still one after replacement.
+	//
+	// The first argument is a list of currently open groups that contain the
+	// Attr. It must not be retained or modified. ReplaceAttr is never called
+	// for Group attributes, only their contents.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
 }
 
 type Handler struct {
-	opts    HandlerOptions
-	out     io.Writer
-	group   string
-	context buffer
-	enc     *encoder
+	opts      HandlerOptions
+	out       io.Writer
+	mu        *sync.Mutex // shared with derived handlers, guards out
+	groups    []string
+	context   buffer
+	block     buffer     // continuation lines pre-rendered by WithAttrs
+	prefix    slog.Value // value of the PrefixKey attribute added with WithAttrs
+	hasPrefix bool
+	enc       *encoder
 }
 
 var _ slog.Handler = (*Handler)(nil)
@@ -64,14 +239,32 @@ func NewHandler(out io.Writer, opts *HandlerOptions) *Handler {
 		opts.TimeFormat = time.DateTime
 	}
 	if opts.Theme == nil {
-		opts.Theme = NewDefaultTheme()
+		if theme, err := ThemeFromEnv(); err == nil && theme != nil {
+			opts.Theme = theme
+		} else if opts.AutoTheme && !opts.NoColor && opts.ColorMode.enabled(out) {
+			opts.Theme = DetectTheme(out)
+		} else {
+			opts.Theme = NewDefaultTheme()
+		}
 	}
+	o := *opts // Copy struct
+	o.NoColor = o.NoColor || !o.ColorMode.enabled(out)
+	layout, err := parseLayout(o.Layout)
+	if err != nil {
+		panic("console: " + err.Error())
+	}
+	enc := newEncoder(o)
+	enc.layout = layout
 	return &Handler{
-		opts:    *opts, // Copy struct
-		out:     out,
-		group:   "",
-		context: nil,
-		enc:     &encoder{opts: *opts},
+		opts:      o,
+		out:       out,
+		mu:        new(sync.Mutex),
+		groups:    nil,
+		context:   nil,
+		block:     nil,
+		prefix:    slog.Value{},
+		hasPrefix: false,
+		enc:       enc,
 	}
 }
 
@@ -83,20 +276,20 @@ func (h *Handler) Enabled(_ context.Context, l slog.Level) bool {
 // Handle implements slog.Handler.
 func (h *Handler) Handle(_ context.Context, rec slog.Record) error {
 	buf := bufferPool.Get().(*buffer)
+	block := bufferPool.Get().(*buffer)
 
-	h.enc.writeTimestamp(buf, rec.Time)
-	h.enc.writeLevel(buf, rec.Level)
-	if h.opts.AddSource && rec.PC > 0 {
-		h.enc.writeSource(buf, rec.PC, cwd)
+	h.writeLayout(buf, block, &rec)
+	if h.opts.StackTraceLevel != nil && rec.Level >= h.opts.StackTraceLevel.Level() {
+		h.enc.writeCallerStackTrace(block, callerStackTrace(rec.PC, h.enc.stackTraceMaxDepth()))
 	}
-	h.enc.writeMessage(buf, rec.Level, rec.Message)
-	buf.copy(&h.context)
-	rec.Attrs(func(a slog.Attr) bool {
-		h.enc.writeAttr(buf, a, h.group)
-		return true
-	})
 	h.enc.NewLine(buf)
-	if _, err := buf.WriteTo(h.out); err != nil {
+	h.enc.writeBlock(buf, rec.Level, block)
+	block.Reset()
+	bufferPool.Put(block)
+	h.mu.Lock()
+	_, err := buf.WriteTo(h.out)
+	h.mu.Unlock()
+	if err != nil {
 		buf.Reset()
 		bufferPool.Put(buf)
 		return err
@@ -105,33 +298,121 @@ func (h *Handler) Handle(_ context.Context, rec slog.Record) error {
 	return nil
 }
 
+// writeLayout writes the elements of the record as placed by the Layout option.
+func (h *Handler) writeLayout(buf, block *buffer, rec *slog.Record) {
+	h.enc.writeLiteral(buf, h.enc.layout.head)
+	written, sep := false, ""
+	for i := range h.enc.layout.segments {
+		seg := &h.enc.layout.segments[i]
+		start := buf.Len()
+		if written {
+			h.enc.writeLiteral(buf, sep)
+		}
+		h.enc.writeLiteral(buf, seg.prefix)
+		if !h.writeElement(buf, block, seg.element, rec) {
+			*buf = (*buf)[:start]
+			continue
+		}
+		h.enc.writeLiteral(buf, seg.suffix)
+		written, sep = true, seg.sep
+	}
+	h.enc.writeLiteral(buf, h.enc.layout.tail)
+}
+
+// writeElement writes an element of the record, as placed by the Layout option.
+// It reports whether anything was written in buf.
+func (h *Handler) writeElement(buf, block *buffer, el layoutElement, rec *slog.Record) bool {
+	switch el {
+	case layoutTime:
+		return h.enc.writeTimestamp(buf, rec.Time)
+	case layoutLevel:
+		return h.enc.writeLevel(buf, rec.Level)
+	case layoutSource:
+		return h.opts.AddSource && rec.PC > 0 && h.enc.writeSource(buf, rec.PC, cwd)
+	case layoutPrefix:
+		if h.opts.PrefixKey == "" {
+			return false
+		}
+		prefix, hasPrefix := h.prefix, h.hasPrefix
+		var groupsArr [8]string
+		groups := append(groupsArr[:0], h.groups...)
+		rec.Attrs(func(a slog.Attr) bool {
+			if value, ok, found := h.enc.findPrefix(a, groups); found {
+				prefix, hasPrefix = value, ok
+			}
+			return true
+		})
+		return h.enc.writePrefix(buf, prefix, hasPrefix)
+	case layoutMessage:
+		return h.enc.writeMessage(buf, block, rec.Level, rec.Message)
+	case layoutAttrs:
+		start := buf.Len()
+		buf.copy(&h.context)
+		block.copy(&h.block)
+		// Copy the open groups to a stack allocated array so that nested groups
+		// found in the record can be pushed without allocating.
+		var groupsArr [8]string
+		groups := append(groupsArr[:0], h.groups...)
+		rec.Attrs(func(a slog.Attr) bool {
+			h.enc.writeAttr(buf, block, a, groups)
+			return true
+		})
+		if buf.Len() == start {
+			return false
+		}
+		// Drop the space attributes start with, the layout separates them.
+		*buf = append((*buf)[:start], (*buf)[start+1:]...)
+		return true
+	}
+	return false
+}
+
 // WithAttrs implements slog.Handler.
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newCtx := h.context
+	newBlock := h.block
+	prefix, hasPrefix := h.prefix, h.hasPrefix
 	for _, a := range attrs {
-		h.enc.writeAttr(&newCtx, a, h.group)
+		if h.opts.PrefixKey != "" {
+			if value, ok, found := h.enc.findPrefix(a, h.groups[:len(h.groups):len(h.groups)]); found {
+				prefix, hasPrefix = value, ok
+			}
+		}
+		// The groups are capped so that the groups of attributes can't be
+		// pushed to the spare capacity, which is shared with other handlers.
+		h.enc.writeAttr(&newCtx, &newBlock, a, h.groups[:len(h.groups):len(h.groups)])
 	}
 	newCtx.Clip()
+	newBlock.Clip()
 	return &Handler{
-		opts:    h.opts,
-		out:     h.out,
-		group:   h.group,
-		context: newCtx,
-		enc:     h.enc,
+		opts:      h.opts,
+		out:       h.out,
+		mu:        h.mu,
+		groups:    h.groups,
+		context:   newCtx,
+		block:     newBlock,
+		prefix:    prefix,
+		hasPrefix: hasPrefix,
+		enc:       h.enc,
 	}
 }
 
 // WithGroup implements slog.Handler.
+// If name is empty, WithGroup returns the receiver.
 func (h *Handler) WithGroup(name string) slog.Handler {
 	name = strings.TrimSpace(name)
-	if h.group != "" {
-		name = h.group + "." + name
+	if name == "" {
+		return h
 	}
 	return &Handler{
-		opts:    h.opts,
-		out:     h.out,
-		group:   name,
-		context: h.context,
-		enc:     h.enc,
+		opts:      h.opts,
+		out:       h.out,
+		mu:        h.mu,
+		groups:    append(h.groups[:len(h.groups):len(h.groups)], name),
+		context:   h.context,
+		block:     h.block,
+		prefix:    h.prefix,
+		hasPrefix: h.hasPrefix,
+		enc:       h.enc,
 	}
 }
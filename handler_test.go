@@ -5,11 +5,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"testing/slogtest"
 	"time"
 )
 
@@ -102,7 +107,7 @@ func TestHandler_Attr(t *testing.T) {
 	)
 	AssertNoError(t, h.Handle(context.Background(), rec))
 
-	expected := fmt.Sprintf("%s INF foobar bool=true int=-12 uint=12 float=3.14 foo=bar time=%s dur=1s group.foo=bar group.subgroup.foo=bar err=the error stringer=stringer nostringer={bar} valuer=The word is 'distant'\n", now.Format(time.DateTime), now.Format(time.DateTime))
+	expected := fmt.Sprintf("%s INF foobar bool=true int=-12 uint=12 float=3.14 foo=bar time=\"%s\" dur=1s group.foo=bar group.subgroup.foo=bar err=\"the error\" stringer=stringer nostringer={bar} valuer=\"The word is 'distant'\"\n", now.Format(time.DateTime), now.Format(time.DateTime))
 	AssertEqual(t, expected, buf.String())
 }
 
@@ -154,7 +159,7 @@ func TestHandler_GroupResolve(t *testing.T) {
 	)
 	AssertNoError(t, h.Handle(context.Background(), rec))
 
-	expected := fmt.Sprintf("%s INF foobar group.stringer=stringer group.valuer=The word is 'surreal'\n", now.Format(time.DateTime))
+	expected := fmt.Sprintf("%s INF foobar group.stringer=stringer group.valuer=\"The word is 'surreal'\"\n", now.Format(time.DateTime))
 	AssertEqual(t, expected, buf.String())
 }
 
@@ -187,7 +192,7 @@ func TestHandler_WithAttr(t *testing.T) {
 		)})
 	AssertNoError(t, h2.Handle(context.Background(), rec))
 
-	expected := fmt.Sprintf("%s INF foobar bool=true int=-12 uint=12 float=3.14 foo=bar time=%s dur=1s stringer=stringer valuer=The word is 'awesome' group.foo=bar group.subgroup.foo=bar group.stringer=stringer group.valuer=The word is 'pizza'\n", now.Format(time.DateTime), now.Format(time.DateTime))
+	expected := fmt.Sprintf("%s INF foobar bool=true int=-12 uint=12 float=3.14 foo=bar time=\"%s\" dur=1s stringer=stringer valuer=\"The word is 'awesome'\" group.foo=bar group.subgroup.foo=bar group.stringer=stringer group.valuer=\"The word is 'pizza'\"\n", now.Format(time.DateTime), now.Format(time.DateTime))
 	AssertEqual(t, expected, buf.String())
 
 	buf.Reset()
@@ -217,6 +222,337 @@ func TestHandler_WithGroup(t *testing.T) {
 	AssertEqual(t, fmt.Sprintf("%s INF foobar int=12\n", now.Format(time.DateTime)), buf.String())
 }
 
+func TestHandler_WithGroupEmpty(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true})
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+	rec.Add("int", 12, slog.Group("", "foo", "bar"))
+	AssertEqual[slog.Handler](t, h, h.WithGroup(""))
+
+	h2 := h.WithGroup("group1").WithGroup("").WithAttrs([]slog.Attr{slog.Group("", slog.String("foo", "bar"))})
+	AssertNoError(t, h2.Handle(context.Background(), rec))
+	AssertEqual(t, "INF foobar group1.foo=bar group1.int=12 group1.foo=bar\n", buf.String())
+}
+
+func TestHandler_QuoteMode(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		mode     QuoteMode
+		expected string
+	}{
+		{
+			mode:     QuoteAuto,
+			expected: `INF foobar str=foo empty="" space="hello world" eq="a=b" quote="say \"hi\"" nl="line1\nline2" tab="a\tb" utf8="\xff" unicode=héhé int=12 err="the error" time="%[1]s" "my key"=foo "my group.key"=bar`,
+		},
+		{
+			mode:     QuoteAlways,
+			expected: `INF foobar str="foo" empty="" space="hello world" eq="a=b" quote="say \"hi\"" nl="line1\nline2" tab="a\tb" utf8="\xff" unicode="héhé" int=12 err="the error" time="%[1]s" "my key"="foo" "my group.key"="bar"`,
+		},
+		{
+			mode:     QuoteNever,
+			expected: `INF foobar str=foo empty= space=hello world eq=a=b quote=say "hi" nl=line1\nline2 tab=a\tb utf8=\xff unicode=héhé int=12 err=the error time=%[1]s my key=foo my group.key=bar`,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			buf := bytes.Buffer{}
+			h := NewHandler(&buf, &HandlerOptions{NoColor: true, QuoteMode: tc.mode})
+			rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+			rec.Add(
+				"str", "foo",
+				"empty", "",
+				"space", "hello world",
+				"eq", "a=b",
+				"quote", `say "hi"`,
+				"nl", "line1\nline2",
+				"tab", "a\tb",
+				"utf8", "\xff",
+				"unicode", "héhé",
+				"int", 12,
+				"err", errors.New("the error"),
+				"time", now,
+				"my key", "foo",
+				slog.Group("my group", "key", "bar"),
+			)
+			AssertNoError(t, h.Handle(context.Background(), rec))
+			AssertEqual(t, fmt.Sprintf(tc.expected, now.Format(time.DateTime))+"\n", buf.String())
+		})
+	}
+}
+
+func TestHandler_Sanitize(t *testing.T) {
+	const evil = "\x1b[2K\x1b]0;pwned\x07\u009b31m\u202e"
+	const escaped = `\x1b[2K\x1b]0;pwned\a\u009b31m\u202e`
+	tests := []struct {
+		name     string
+		opts     HandlerOptions
+		expected string
+	}{
+		{
+			name:     "quote auto",
+			opts:     HandlerOptions{NoColor: true},
+			expected: "INF msg" + escaped + ` "key` + escaped + `"="val` + escaped + `" "grp` + escaped + `.k"=v` + "\n",
+		},
+		{
+			name:     "quote never",
+			opts:     HandlerOptions{NoColor: true, QuoteMode: QuoteNever},
+			expected: "INF msg" + escaped + " key" + escaped + "=val" + escaped + " grp" + escaped + ".k=v\n",
+		},
+		{
+			name:     "no sanitize",
+			opts:     HandlerOptions{NoColor: true, QuoteMode: QuoteNever, NoSanitize: true},
+			expected: "INF msg" + evil + " key" + evil + "=val" + evil + " grp" + evil + ".k=v\n",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			h := NewHandler(&buf, &tc.opts)
+			rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg"+evil, 0)
+			rec.Add("key"+evil, "val"+evil, slog.Group("grp"+evil, "k", "v"))
+			AssertNoError(t, h.Handle(context.Background(), rec))
+			AssertEqual(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestHandler_Multiline(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, Multiline: true}).
+		WithAttrs([]slog.Attr{slog.String("tpl", "{{ .Foo }}\n{{ .Bar }}\n")}).
+		WithGroup("db")
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "query failed\nwhile loading users\r\n", 0)
+	rec.Add("rows", 0, "query", "SELECT *\n  FROM users\n\nWHERE id = '\x1b'", "err", errors.New("line1\nline2"))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	expected := "INF query failed db.rows=0\n" +
+		"  │ while loading users\n" +
+		"  │ tpl=\n" +
+		"  │   {{ .Foo }}\n" +
+		"  │   {{ .Bar }}\n" +
+		"  │ db.query=\n" +
+		"  │   SELECT *\n" +
+		"  │     FROM users\n" +
+		"  │   \n" +
+		"  │   WHERE id = '\\x1b'\n" +
+		"  │ db.err=\n" +
+		"  │   line1\n" +
+		"  │   line2\n"
+	AssertEqual(t, expected, buf.String())
+
+	// Single line records are left untouched
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, Multiline: true})
+	rec = slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+	rec.Add("foo", "bar baz")
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, "INF foobar foo=\"bar baz\"\n", buf.String())
+
+	// The gutter is colored after the level
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Multiline: true})
+	rec = slog.NewRecord(time.Time{}, slog.LevelError, "foo\nbar", 0)
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	theme := NewDefaultTheme()
+	expected = fmt.Sprintf("%[1]sERR%[3]s %[2]sfoo%[3]s\n  %[1]s│%[3]s %[2]sbar%[3]s\n", theme.LevelError(), theme.Message(), ResetMod)
+	AssertEqual(t, expected, buf.String())
+}
+
+func TestHandler_ExpandErrors(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "config.yml", Err: fs.ErrNotExist}
+	err := fmt.Errorf("load config: %w", errors.Join(
+		fmt.Errorf("read file: %w", pathErr),
+		errors.Join(errors.New("fallback failed"), errors.New("defaults failed")),
+		errors.New("giving up"),
+	))
+
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, ExpandErrors: true})
+	rec := slog.NewRecord(time.Time{}, slog.LevelError, "foobar", 0)
+	rec.Add("err", err, "plain", errors.New("not wrapping"), "id", 12)
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	expected := `ERR foobar plain="not wrapping" id=12
+  │ err=
+  │   load config
+  │   ├─ read file
+  │   │  └─ open config.yml
+  │   │     └─ file does not exist
+  │   ├─ fallback failed
+  │   ├─ defaults failed
+  │   └─ giving up
+`
+	AssertEqual(t, expected, buf.String())
+
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, ExpandErrors: true, ErrorTypes: true})
+	rec = slog.NewRecord(time.Time{}, slog.LevelError, "foobar", 0)
+	rec.Add("err", errors.Join(pathErr, errors.New("other")))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	expected = `ERR foobar
+  │ err=
+  │   ├─ open config.yml (*fs.PathError)
+  │   │  └─ file does not exist (*errors.errorString)
+  │   └─ other (*errors.errorString)
+`
+	AssertEqual(t, expected, buf.String())
+
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true})
+	rec = slog.NewRecord(time.Time{}, slog.LevelError, "foobar", 0)
+	rec.Add("err", pathErr)
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, "ERR foobar err=\"open config.yml: file does not exist\"\n", buf.String())
+
+	// Branches are styled with ElementErrorTree, or like timestamps if unset
+	for style, theme := range map[ANSIMod]ThemeDef{
+		ToANSICode(Red):         NewTheme(NewDefaultTheme()).With(ElementErrorTree, ToANSICode(Red)),
+		ToANSICode(BrightBlack): NewTheme(NewDefaultTheme()).With(ElementErrorTree, ""),
+	} {
+		h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, ExpandErrors: true, Theme: theme})
+		out := logAttrs(t, &buf, h, "foobar", slog.Any("err", errors.Join(errors.New("a"), errors.New("b"))))
+		AssertEqual(t, true, strings.Contains(out, "  "+string(style)+"├─ "))
+	}
+}
+
+type callersError struct{ pcs []uintptr }
+
+func (e *callersError) Error() string      { return "callers error" }
+func (e *callersError) Callers() []uintptr { return e.pcs }
+
+type pkgFrame uintptr
+
+type pkgStackError struct{ pcs []uintptr }
+
+func (e *pkgStackError) Error() string { return "pkg error" }
+func (e *pkgStackError) StackTrace() []pkgFrame {
+	st := make([]pkgFrame, len(e.pcs))
+	for i, pc := range e.pcs {
+		st[i] = pkgFrame(pc)
+	}
+	return st
+}
+
+type framesError struct{ frames []runtime.Frame }
+
+func (e *framesError) Error() string                { return "frames error" }
+func (e *framesError) StackFrames() []runtime.Frame { return e.frames }
+
+func TestHandler_ErrorStackTraces(t *testing.T) {
+	var pcs [32]uintptr
+	n := runtime.Callers(1, pcs[:])
+	_, file, line, _ := runtime.Caller(0)
+	cwd, _ := os.Getwd()
+	file, _ = filepath.Rel(cwd, file)
+	here := fmt.Sprintf("  github.com/phsym/console-slog.TestHandler_ErrorStackTraces %s:%d\n", file, line-1)
+
+	for _, err := range []error{
+		&callersError{pcs[:n]},
+		&pkgStackError{pcs[:n]},
+		fmt.Errorf("wrapped: %w", &pkgStackError{pcs[:n]}),
+		&framesError{[]runtime.Frame{{Function: "github.com/phsym/console-slog.TestHandler_ErrorStackTraces", File: filepath.Join(cwd, file), Line: line - 1}}},
+	} {
+		buf := bytes.Buffer{}
+		h := NewHandler(&buf, &HandlerOptions{NoColor: true, ErrorStackTraces: true})
+		rec := slog.NewRecord(time.Time{}, slog.LevelError, "foobar", 0)
+		rec.Add("err", err)
+		AssertNoError(t, h.Handle(context.Background(), rec))
+		header, stack, _ := strings.Cut(buf.String(), "\n")
+		AssertEqual(t, fmt.Sprintf("ERR foobar err=%q", err.Error()), header)
+		if !strings.HasPrefix(stack, "  │ err stack trace:\n  │ "+here) {
+			t.Errorf("unexpected stack trace:\n%s", stack)
+		}
+		if _, ok := err.(*framesError); !ok && (!strings.Contains(stack, "  │   testing.tRunner ") || !strings.HasSuffix(stack, "  │     ... 1 more\n")) {
+			t.Errorf("standard library frames not collapsed:\n%s", stack)
+		}
+	}
+
+	// Standard library frames are styled with ElementStackFrameStd
+	buf := bytes.Buffer{}
+	red := string(ToANSICode(Red))
+	theme := NewTheme(NewDefaultTheme()).With(ElementStackFrameStd, ToANSICode(Red))
+	out := logAttrs(t, &buf, NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, ErrorStackTraces: true, Theme: theme}), "foobar", slog.Any("err", &callersError{pcs[:n]}))
+	AssertEqual(t, true, strings.Contains(out, "  "+red+"testing.tRunner"))
+	AssertEqual(t, true, strings.Contains(out, red+"... 1 more"))
+
+	buf.Reset()
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true})
+	rec := slog.NewRecord(time.Time{}, slog.LevelError, "foobar", 0)
+	rec.Add("err", &callersError{pcs[:n]})
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, "ERR foobar err=\"callers error\"\n", buf.String())
+}
+
+func TestHandler_StackTraceLevel(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, StackTraceLevel: slog.LevelError, StackTraceMaxDepth: 1})
+	l := slog.New(h)
+	l.Info("foobar")
+	AssertEqual(t, "INF foobar\n", strings.SplitN(buf.String(), " ", 3)[2])
+
+	buf.Reset()
+	_, file, line, _ := runtime.Caller(0)
+	l.Error("foobar")
+	cwd, _ := os.Getwd()
+	file, _ = filepath.Rel(cwd, file)
+	lines := strings.Split(buf.String(), "\n")
+	AssertEqual(t, 5, len(lines))
+	AssertEqual(t, "ERR foobar", strings.SplitN(lines[0], " ", 3)[2])
+	AssertEqual(t, "  │ stack trace:", lines[1])
+	AssertEqual(t, fmt.Sprintf("  │   github.com/phsym/console-slog.TestHandler_StackTraceLevel %s:%d", file, line+1), lines[2])
+	AssertEqual(t, "  │     ... 2 more", lines[3])
+
+	// Records created without a PC get the stack trace of the caller of Handle
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, StackTraceLevel: slog.LevelError})
+	_, _, line, _ = runtime.Caller(0)
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelError, "foobar", 0)))
+	lines = strings.Split(buf.String(), "\n")
+	AssertEqual(t, fmt.Sprintf("  │   github.com/phsym/console-slog.TestHandler_StackTraceLevel %s:%d", file, line+1), lines[2])
+}
+
+type panickingStringer struct{}
+
+func (panickingStringer) String() string { panic("boom") }
+
+type panickingValuer struct{}
+
+func (panickingValuer) LogValue() slog.Value { panic("boom") }
+
+type panickingError struct{}
+
+func (*panickingError) Error() string { return "wrapper" }
+func (*panickingError) Unwrap() error { panic("boom") }
+
+type valueError struct{ msg string }
+
+func (e valueError) Error() string { return e.msg }
+
+func TestHandler_PanicSafe(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, ExpandErrors: true, ErrorStackTraces: true})
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+	rec.Add(
+		"nilstringer", (*theStringer)(nil),
+		"nilerr", (*valueError)(nil),
+		"nilvaluer", (*theValuer)(nil),
+		"stringer", panickingStringer{},
+		"valuer", panickingValuer{},
+		"err", &panickingError{},
+		"foo", "bar",
+	)
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, "INF foobar nilstringer=<nil> nilerr=<nil> nilvaluer=<nil> stringer=!PANIC(boom) valuer=!PANIC(boom) err=wrapper foo=bar\n", buf.String())
+
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways})
+	rec = slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+	rec.Add("stringer", panickingStringer{})
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	theme := NewDefaultTheme()
+	AssertEqual(t, true, strings.HasSuffix(buf.String(), string(theme.AttrValueError())+"!PANIC(boom)"+string(ResetMod)+"\n"))
+}
+
 func TestHandler_Levels(t *testing.T) {
 	levels := map[slog.Level]string{
 		slog.LevelDebug - 1: "DBG-1",
@@ -248,6 +584,57 @@ func TestHandler_Levels(t *testing.T) {
 	}
 }
 
+func TestHandler_LevelNames(t *testing.T) {
+	names := FullLevelNames()
+	names[slog.Level(-8)] = "TRACE"
+	names[slog.Level(12)] = "FATAL"
+	levels := map[slog.Level]string{
+		slog.Level(-9):      "TRACE-1",
+		slog.Level(-8):      "TRACE",
+		slog.LevelDebug:     "DEBUG",
+		slog.LevelInfo:      "INFO ",
+		slog.LevelInfo + 1:  "INFO+1",
+		slog.LevelWarn:      "WARN ",
+		slog.LevelError:     "ERROR",
+		slog.LevelError + 1: "ERROR+1",
+		slog.Level(12):      "FATAL",
+		slog.Level(13):      "FATAL+1",
+	}
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{Level: slog.Level(-10), NoColor: true, LevelNames: names})
+	for l, s := range levels {
+		AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, l, "foobar", 0)))
+		AssertEqual(t, s+" foobar\n", buf.String())
+		buf.Reset()
+	}
+
+	h = NewHandler(&buf, &HandlerOptions{NoColor: true, LevelNames: LetterLevelNames()})
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelWarn, "foobar", 0)))
+	AssertEqual(t, "W foobar\n", buf.String())
+}
+
+func TestWithLevelStyles(t *testing.T) {
+	trace, fatal := ToANSICode(Faint), ToANSICode(Bold, BrightRed)
+	theme := WithLevelStyles(NewDefaultTheme(), map[slog.Level]ANSIMod{
+		slog.Level(-8): trace,
+		slog.Level(12): fatal,
+	})
+	def := NewDefaultTheme()
+	AssertEqual(t, "Default", theme.Name())
+	AssertEqual(t, trace, theme.Level(slog.Level(-8)))
+	AssertEqual(t, trace, theme.Level(slog.Level(-5)))
+	AssertEqual(t, def.LevelDebug(), theme.Level(slog.LevelDebug))
+	AssertEqual(t, def.LevelError(), theme.Level(slog.LevelError+3))
+	AssertEqual(t, fatal, theme.Level(slog.Level(12)))
+	AssertEqual(t, fatal, theme.Level(slog.Level(13)))
+	AssertEqual(t, def.StackFrame(), theme.StackFrame())
+
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Theme: theme, LevelNames: LevelNames{slog.Level(12): "FTL", slog.LevelInfo: "INF"}})
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.Level(12), "foobar", 0)))
+	AssertEqual(t, fmt.Sprintf("%sFTL%s %sfoobar%s\n", fatal, ResetMod, def.Message(), ResetMod), buf.String())
+}
+
 func TestHandler_Source(t *testing.T) {
 	buf := bytes.Buffer{}
 	h := NewHandler(&buf, &HandlerOptions{NoColor: true, AddSource: true})
@@ -278,11 +665,64 @@ func TestHandler_Err(t *testing.T) {
 	AssertError(t, h.Handle(context.Background(), rec))
 }
 
+func TestHandler_Concurrent(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true})
+	handlers := []slog.Handler{
+		h,
+		h.WithAttrs([]slog.Attr{slog.String("foo", "bar")}),
+		h.WithGroup("group"),
+		h.WithGroup("group").WithAttrs([]slog.Attr{slog.String("foo", "bar")}),
+	}
+	const goroutines, records = 8, 200
+	wg := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(hdl slog.Handler, id int) {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+				rec.Add("id", id, "seq", j)
+				AssertNoError(t, hdl.Handle(context.Background(), rec))
+			}
+		}(handlers[i%len(handlers)], i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	AssertEqual(t, goroutines*records, len(lines))
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "INF foobar ") || strings.Count(line, "INF") != 1 || !strings.Contains(line, "seq=") {
+			t.Fatalf("corrupted line %q", line)
+		}
+	}
+}
+
+func TestHandler_ConcurrentWithAttrs(t *testing.T) {
+	// Three groups leave spare capacity in the groups slice of the handler.
+	h := NewHandler(io.Discard, &HandlerOptions{NoColor: true}).WithGroup("g1").WithGroup("g2").WithGroup("g3")
+	const goroutines, iterations = 8, 200
+	wg := sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			name := fmt.Sprintf("sub%d", id)
+			for j := 0; j < iterations; j++ {
+				buf := bytes.Buffer{}
+				hdl := h.WithAttrs([]slog.Attr{slog.Group(name, "id", id)}).(*Handler)
+				hdl.out = &buf
+				AssertNoError(t, hdl.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)))
+				AssertEqual(t, fmt.Sprintf("INF foobar g1.g2.g3.%s.id=%d\n", name, id), buf.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
 func TestThemes(t *testing.T) {
-	for _, theme := range []Theme{
-		NewDefaultTheme(),
-		NewBrightTheme(),
-	} {
+	for _, registered := range Themes() {
+		theme := NewTheme(registered)
 		t.Run(theme.Name(), func(t *testing.T) {
 			level := slog.LevelInfo
 			rec := slog.Record{}
@@ -296,6 +736,8 @@ func TestThemes(t *testing.T) {
 				AddSource:  true,
 				TimeFormat: timeFormat,
 				Theme:      theme,
+				ColorMode:  ColorAlways,
+				ColorDepth: ColorDepthTrueColor,
 			}).WithAttrs([]slog.Attr{{Key: "pid", Value: slog.IntValue(37556)}})
 			var pcs [1]uintptr
 			runtime.Callers(1, pcs[:])
@@ -315,7 +757,18 @@ func TestThemes(t *testing.T) {
 				})
 			}
 
-			checkLog := func(level slog.Level, attrCount int) {
+			// valueStyle returns the style of values rendered as el,
+			// falling back to the AttrValue style.
+			valueStyle := func(el Element) ANSIMod {
+				if style := theme.Style(el); style != "" {
+					return style
+				}
+				return theme.AttrValue()
+			}
+			number, str := valueStyle(ElementAttrValueNumber), valueStyle(ElementAttrValueString)
+			duration := valueStyle(ElementAttrValueDuration)
+
+			checkLog := func(level slog.Level, valueStyles ...ANSIMod) {
 				t.Run("CheckLog_"+level.String(), func(t *testing.T) {
 					println("log: ", string(buf.Bytes()))
 
@@ -346,15 +799,15 @@ func TestThemes(t *testing.T) {
 						}
 					}
 
-					for i := 0; i < attrCount; i++ {
+					for _, attrValue := range valueStyles {
 						// AttrKey
 						if theme.AttrKey() != "" {
 							checkANSIMod(t, "AttrKey", theme.AttrKey())
 						}
 
 						// AttrValue
-						if theme.AttrValue() != "" {
-							checkANSIMod(t, "AttrValue", theme.AttrValue())
+						if attrValue != "" {
+							checkANSIMod(t, "AttrValue", attrValue)
 						}
 					}
 				})
@@ -366,7 +819,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("database", "myapp", "host", "localhost:4962")
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 3)
+			checkLog(level, number, str, str)
 
 			buf.Reset()
 			level = slog.LevelDebug
@@ -374,7 +827,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("database", "myapp", "host", "localhost:4962")
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 3)
+			checkLog(level, number, str, str)
 
 			buf.Reset()
 			level = slog.LevelDebug + 1
@@ -382,7 +835,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("database", "myapp", "host", "localhost:4962")
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 3)
+			checkLog(level, number, str, str)
 
 			buf.Reset()
 			level = slog.LevelInfo
@@ -390,7 +843,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("listen", ":8080")
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 2)
+			checkLog(level, number, str)
 
 			buf.Reset()
 			level = slog.LevelInfo + 1
@@ -398,7 +851,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("method", "GET", "path", "/users", "resp_time", time.Millisecond*10)
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 4)
+			checkLog(level, number, str, str, duration)
 
 			buf.Reset()
 			level = slog.LevelWarn
@@ -406,7 +859,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("method", "POST", "path", "/posts", "resp_time", time.Second*532)
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 4)
+			checkLog(level, number, str, str, duration)
 
 			buf.Reset()
 			level = slog.LevelWarn + 1
@@ -414,7 +867,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("method", "POST", "path", "/posts", "resp_time", time.Second*532)
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 4)
+			checkLog(level, number, str, str, duration)
 
 			buf.Reset()
 			level = slog.LevelError
@@ -422,7 +875,7 @@ func TestThemes(t *testing.T) {
 			rec.Add("database", "myapp", "error", errors.New("connection reset by peer"))
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 3)
+			checkLog(level, number, str, theme.AttrValueError())
 
 			buf.Reset()
 			level = slog.LevelError + 1
@@ -430,7 +883,190 @@ func TestThemes(t *testing.T) {
 			rec.Add("database", "myapp", "error", errors.New("connection reset by peer"))
 			h.Handle(context.Background(), rec)
 			bufBytes = buf.Bytes()
-			checkLog(level, 3)
+			checkLog(level, number, str, theme.AttrValueError())
+		})
+	}
+}
+
+func TestHandler_ReplaceAttr(t *testing.T) {
+	pc, file, line, _ := runtime.Caller(0)
+	cwd, _ := os.Getwd()
+	file, _ = filepath.Rel(cwd, file)
+	now := time.Now()
+	sourceField := fmt.Sprintf("%s:%d", file, line)
+
+	replaceAttrWith := func(key string, out slog.Attr) func(groups []string, a slog.Attr) slog.Attr {
+		return func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == key {
+				return out
+			}
+			return a
+		}
+	}
+
+	tests := []struct {
+		name        string
+		replaceAttr func(groups []string, a slog.Attr) slog.Attr
+		want        string
+	}{
+		{
+			name: "no replaceattrs",
+			want: fmt.Sprintf("%s INF %s > foobar size=12 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "not modifying",
+			replaceAttr: func(_ []string, a slog.Attr) slog.Attr { return a },
+			want:        fmt.Sprintf("%s INF %s > foobar size=12 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "change time",
+			replaceAttr: replaceAttrWith(slog.TimeKey, slog.Time(slog.TimeKey, now.Add(time.Hour))),
+			want:        fmt.Sprintf("%s INF %s > foobar size=12 color=red\n", now.Add(time.Hour).Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "time to string",
+			replaceAttr: replaceAttrWith(slog.TimeKey, slog.String(slog.TimeKey, "yesterday")),
+			want:        fmt.Sprintf("yesterday INF %s > foobar size=12 color=red\n", sourceField),
+		},
+		{
+			name:        "drop time",
+			replaceAttr: replaceAttrWith(slog.TimeKey, slog.Attr{}),
+			want:        fmt.Sprintf("INF %s > foobar size=12 color=red\n", sourceField),
+		},
+		{
+			name:        "change level",
+			replaceAttr: replaceAttrWith(slog.LevelKey, slog.Any(slog.LevelKey, slog.LevelWarn)),
+			want:        fmt.Sprintf("%s WRN %s > foobar size=12 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "level to string",
+			replaceAttr: replaceAttrWith(slog.LevelKey, slog.String(slog.LevelKey, "INFO")),
+			want:        fmt.Sprintf("%s INFO %s > foobar size=12 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "drop level",
+			replaceAttr: replaceAttrWith(slog.LevelKey, slog.Attr{}),
+			want:        fmt.Sprintf("%s %s > foobar size=12 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "change source",
+			replaceAttr: replaceAttrWith(slog.SourceKey, slog.Any(slog.SourceKey, &slog.Source{File: filepath.Join(cwd, "other.go"), Line: 42})),
+			want:        fmt.Sprintf("%s INF other.go:42 > foobar size=12 color=red\n", now.Format(time.DateTime)),
+		},
+		{
+			name:        "source to string",
+			replaceAttr: replaceAttrWith(slog.SourceKey, slog.String(slog.SourceKey, "here")),
+			want:        fmt.Sprintf("%s INF here > foobar size=12 color=red\n", now.Format(time.DateTime)),
+		},
+		{
+			name:        "drop source",
+			replaceAttr: replaceAttrWith(slog.SourceKey, slog.Attr{}),
+			want:        fmt.Sprintf("%s INF foobar size=12 color=red\n", now.Format(time.DateTime)),
+		},
+		{
+			name:        "change message",
+			replaceAttr: replaceAttrWith(slog.MessageKey, slog.String(slog.MessageKey, "barbaz")),
+			want:        fmt.Sprintf("%s INF %s > barbaz size=12 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "drop message",
+			replaceAttr: replaceAttrWith(slog.MessageKey, slog.Attr{}),
+			want:        fmt.Sprintf("%s INF %s > size=12 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "change attr",
+			replaceAttr: replaceAttrWith("size", slog.Int("height", 24)),
+			want:        fmt.Sprintf("%s INF %s > foobar height=24 color=red\n", now.Format(time.DateTime), sourceField),
+		},
+		{
+			name:        "drop attr",
+			replaceAttr: replaceAttrWith("size", slog.Attr{}),
+			want:        fmt.Sprintf("%s INF %s > foobar color=red\n", now.Format(time.DateTime), sourceField),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			h := NewHandler(&buf, &HandlerOptions{AddSource: true, NoColor: true, ReplaceAttr: tt.replaceAttr})
+			rec := slog.NewRecord(now, slog.LevelInfo, "foobar", pc)
+			rec.AddAttrs(slog.Int("size", 12), slog.String("color", "red"))
+			AssertNoError(t, h.Handle(context.Background(), rec))
+			AssertEqual(t, tt.want, buf.String())
 		})
 	}
 }
+
+func TestHandler_ReplaceAttr_Groups(t *testing.T) {
+	buf := bytes.Buffer{}
+	var seen []string
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		seen = append(seen, fmt.Sprintf("%v:%s", groups, a.Key))
+		if a.Key == "secret" {
+			a.Value = slog.StringValue("***")
+		}
+		return a
+	}})
+	l := slog.New(h).With("secret", "foo").WithGroup("g1").With(slog.Group("g2", "secret", "bar"))
+	l.Info("foobar", slog.Group("g3", "secret", "baz", "stringer", theStringer{}))
+
+	AssertEqual(t, "INF foobar secret=*** g1.g2.secret=*** g1.g3.secret=*** g1.g3.stringer=stringer\n", buf.String())
+	AssertEqual(t, "[]:secret [g1 g2]:secret []:level []:msg [g1 g3]:secret [g1 g3]:stringer", strings.Join(seen, " "))
+}
+
+func TestHandler_SlogTest(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{NoColor: true, TimeFormat: time.RFC3339Nano})
+	err := slogtest.TestHandler(h, func() []map[string]any {
+		return parseLogEntries(t, buf.String())
+	})
+	AssertNoError(t, err)
+}
+
+// parseLogEntries parses the output of a Handler with no colors and a time
+// format without spaces into maps suitable for slogtest.
+// Dotted attribute keys are expanded into nested maps.
+func parseLogEntries(t *testing.T, s string) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		fields := strings.Fields(line)
+		m := map[string]any{}
+		if len(fields) > 0 {
+			if tt, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+				m[slog.TimeKey] = tt
+				fields = fields[1:]
+			}
+		}
+		if len(fields) > 0 {
+			m[slog.LevelKey] = fields[0]
+			fields = fields[1:]
+		}
+		var msg []string
+		for len(fields) > 0 && !strings.Contains(fields[0], "=") {
+			msg = append(msg, fields[0])
+			fields = fields[1:]
+		}
+		m[slog.MessageKey] = strings.Join(msg, " ")
+		for _, field := range fields {
+			key, value, _ := strings.Cut(field, "=")
+			path := strings.Split(key, ".")
+			dst := m
+			for _, g := range path[:len(path)-1] {
+				sub, ok := dst[g].(map[string]any)
+				if !ok {
+					sub = map[string]any{}
+					dst[g] = sub
+				}
+				dst = sub
+			}
+			dst[path[len(path)-1]] = value
+		}
+		entries = append(entries, m)
+	}
+	return entries
+}
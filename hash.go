@@ -0,0 +1,167 @@
+package console
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// hashPaletteCandidates are the colors of the default hash palette,
+// for terminals supporting 256 colors, with various hues readable on both
+// dark and light backgrounds.
+var hashPaletteCandidates = []uint8{
+	33, 38, 43, 63, 69, 74, 99, 105, 110, 135, 141, 147, 165, 171, 177,
+	201, 207, 213, 130, 136, 172, 178, 208, 214, 64, 70, 76, 106, 112, 35,
+}
+
+// basicHashPaletteCandidates are the colors of the default hash palette,
+// for terminals supporting only the basic colors.
+var basicHashPaletteCandidates = []int{
+	Red, Green, Yellow, Blue, Magenta, Cyan,
+	BrightRed, BrightGreen, BrightYellow, BrightBlue, BrightMagenta, BrightCyan,
+}
+
+// minLevelColorDist is the minimum squared distance between the colors of
+// the default hash palette and the ones used for levels.
+const minLevelColorDist = 80 * 80
+
+// newHashPalette returns the HashPalette option downgraded to the color
+// depth, or the default palette if the option is empty.
+func (e *encoder) newHashPalette() []ANSIMod {
+	if len(e.opts.HashKeys) == 0 && !e.opts.PrefixHash {
+		return nil
+	}
+	var palette []ANSIMod
+	if len(e.opts.HashPalette) > 0 {
+		for _, style := range e.opts.HashPalette {
+			palette = append(palette, downgradeStyle(style, e.depth))
+		}
+		return palette
+	}
+
+	var levelColors [][3]uint8
+	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+	for _, l := range e.theme.levels {
+		levels = append(levels, l.level)
+	}
+	for _, l := range levels {
+		if c, ok := fgColor(e.levelStyle(l)); ok {
+			levelColors = append(levelColors, c)
+		}
+	}
+	var candidates []ANSIMod
+	if e.depth == ColorDepth16 {
+		for _, c := range basicHashPaletteCandidates {
+			candidates = append(candidates, ToANSICode(c))
+		}
+	} else {
+		for _, n := range hashPaletteCandidates {
+			candidates = append(candidates, Color256(n))
+		}
+	}
+candidates:
+	for _, style := range candidates {
+		c, _ := fgColor(style)
+		for _, lc := range levelColors {
+			if sqDist(c[0], c[1], c[2], lc[0], lc[1], lc[2]) < minLevelColorDist {
+				continue candidates
+			}
+		}
+		palette = append(palette, style)
+	}
+	if len(palette) == 0 {
+		return candidates
+	}
+	return palette
+}
+
+// fgColor returns the RGB values of the last foreground color set by style.
+func fgColor(style ANSIMod) (c [3]uint8, ok bool) {
+	for _, seq := range strings.Split(string(style), "\x1b[") {
+		params := strings.Split(strings.TrimSuffix(seq, "m"), ";")
+		num := func(i int) uint8 {
+			if i >= len(params) {
+				return 0
+			}
+			n, _ := strconv.ParseUint(params[i], 10, 8)
+			return uint8(n)
+		}
+		for i := 0; i < len(params); i++ {
+			p, err := strconv.Atoi(params[i])
+			switch {
+			case err != nil:
+			case p >= 30 && p <= 37, p >= 90 && p <= 97:
+				c, ok = palette16[basicColorIndex(p)], true
+			case (p == 38 || p == 48) && i+1 < len(params) && params[i+1] == "5":
+				if p == 38 {
+					r, g, b := color256ToRGB(num(i + 2))
+					c, ok = [3]uint8{r, g, b}, true
+				}
+				i += 2
+			case (p == 38 || p == 48) && i+1 < len(params) && params[i+1] == "2":
+				if p == 38 {
+					c, ok = [3]uint8{num(i + 2), num(i + 3), num(i + 4)}, true
+				}
+				i += 4
+			}
+		}
+	}
+	return c, ok
+}
+
+// basicColorIndex returns the index of the basic color set by the SGR
+// foreground parameter p.
+func basicColorIndex(p int) int {
+	if p >= 90 {
+		return p - 90 + 8
+	}
+	return p - 30
+}
+
+// hashStyle returns the color of value in the hash palette. s is its text,
+// if isText is true.
+func (e *encoder) hashStyle(value slog.Value, s string, isText bool) ANSIMod {
+	if len(e.hashPalette) == 0 {
+		return ""
+	}
+	return e.hashPalette[hashValue(value, s, isText)%uint64(len(e.hashPalette))]
+}
+
+// hashValue returns a hash of value. s is its text, if isText is true.
+func hashValue(value slog.Value, s string, isText bool) uint64 {
+	// 64-bit FNV-1a
+	const offset, prime = 14695981039346656037, 1099511628211
+	h := uint64(offset)
+	if isText {
+		for i := 0; i < len(s); i++ {
+			h = (h ^ uint64(s[i])) * prime
+		}
+	} else {
+		var bits uint64
+		switch value.Kind() {
+		case slog.KindInt64:
+			bits = uint64(value.Int64())
+		case slog.KindUint64:
+			bits = value.Uint64()
+		case slog.KindFloat64:
+			bits = math.Float64bits(value.Float64())
+		case slog.KindBool:
+			if value.Bool() {
+				bits = 1
+			}
+		case slog.KindDuration:
+			bits = uint64(value.Duration())
+		case slog.KindTime:
+			bits = uint64(value.Time().UnixNano())
+		}
+		for i := 0; i < 8; i++ {
+			h = (h ^ (bits >> (8 * i) & 0xff)) * prime
+		}
+	}
+	// Mix the bits, as those of FNV-1a are poorly distributed in low bits.
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h
+}
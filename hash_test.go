@@ -0,0 +1,92 @@
+package console
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFgColor(t *testing.T) {
+	for style, expected := range map[ANSIMod][3]uint8{
+		ToANSICode(Red):                             {205, 0, 0},
+		ToANSICode(Bold, BrightBlue):                {92, 92, 255},
+		ToANSICode(Bold) + Color256(208):            {255, 135, 0},
+		RGB(1, 2, 3) + BgRGB(4, 5, 6):               {1, 2, 3},
+		BgColor256(1) + ToANSICode(Green, BgYellow): {0, 205, 0},
+	} {
+		c, ok := fgColor(style)
+		AssertEqual(t, true, ok)
+		AssertEqual(t, expected, c)
+	}
+	for _, style := range []ANSIMod{"", ToANSICode(Bold), ToANSICode(BgRed), BgRGB(1, 2, 3)} {
+		_, ok := fgColor(style)
+		AssertEqual(t, false, ok)
+	}
+}
+
+func TestHandler_HashKeys(t *testing.T) {
+	buf := bytes.Buffer{}
+	valueStyle := func(out, key string) ANSIMod {
+		// Keys are preceded by the end of their style
+		i := strings.Index(out, "m"+key+"="+string(ResetMod))
+		AssertNotEqual(t, -1, i)
+		rest := out[i+1+len(key)+1+len(ResetMod):]
+		if !strings.HasPrefix(rest, "\x1b[") {
+			return ""
+		}
+		return ANSIMod(rest[:strings.IndexByte(rest, 'm')+1])
+	}
+
+	for _, depth := range []ColorDepth{ColorDepth16, ColorDepth256} {
+		opts := &HandlerOptions{ColorMode: ColorAlways, ColorDepth: depth, HashKeys: []string{"request_id", "*.worker", "n"}}
+		palette := NewHandler(nil, opts).enc.hashPalette
+		if depth == ColorDepth16 {
+			// Red, green and yellow are used for levels by the default theme
+			AssertEqual(t, 6, len(palette))
+			for _, c := range []int{Red, Green, Yellow, BrightRed, BrightGreen, BrightYellow} {
+				AssertEqual(t, false, slices.Contains(palette, ToANSICode(c)))
+			}
+		} else {
+			AssertGreaterOrEqual(t, 15, len(palette))
+		}
+
+		seen := map[ANSIMod]bool{}
+		for i := 0; i < 500; i++ {
+			id := slog.String("request_id", "req-"+strconv.Itoa(i))
+			style := valueStyle(logAttrs(t, &buf, NewHandler(&buf, opts), "msg", id), "request_id")
+			AssertEqual(t, true, slices.Contains(palette, style))
+			// Colors are stable
+			AssertEqual(t, style, valueStyle(logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("other", ""), id), "request_id"))
+			seen[style] = true
+		}
+		AssertEqual(t, len(palette), len(seen))
+
+		out := logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Group("pool", slog.String("worker", "w1")), slog.Int("n", 42), slog.String("worker", "w1"))
+		AssertEqual(t, true, slices.Contains(palette, valueStyle(out, "pool.worker")))
+		AssertEqual(t, true, slices.Contains(palette, valueStyle(out, "n")))
+		AssertEqual(t, ANSIMod(""), valueStyle(out, "worker"))
+	}
+
+	// Custom palette, and KeyStyles taking precedence
+	purple := Color256(93)
+	opts := &HandlerOptions{ColorMode: ColorAlways, HashKeys: []string{"id", "user"}, HashPalette: []ANSIMod{purple}, ColorDepth: ColorDepth256,
+		KeyStyles: []KeyStyle{{Key: "user", ValueStyle: ToANSICode(Bold)}}}
+	out := logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("id", "abc"), slog.Bool("user", true))
+	AssertEqual(t, purple, valueStyle(out, "id"))
+	AssertEqual(t, ToANSICode(Bold), valueStyle(out, "user"))
+	opts.ColorDepth = ColorDepth16
+	AssertEqual(t, ToANSICode(Magenta), valueStyle(logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("id", "abc")), "id"))
+
+	// Levels styled with the palette colors are not avoided
+	theme := NewTheme(nil).
+		With(ElementLevelDebug, Color256(33)).With(ElementLevelInfo, Color256(38)).
+		With(ElementLevelWarn, Color256(43)).With(ElementLevelError, Color256(63)).
+		WithLevel(12, Color256(208))
+	palette := NewHandler(nil, &HandlerOptions{ColorDepth: ColorDepth256, HashKeys: []string{"id"}, Theme: theme}).enc.hashPalette
+	for _, n := range []uint8{33, 38, 43, 63, 208, 214} {
+		AssertEqual(t, false, slices.Contains(palette, Color256(n)))
+	}
+}
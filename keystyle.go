@@ -0,0 +1,172 @@
+package console
+
+import (
+	"log/slog"
+	"slices"
+	"unicode/utf8"
+)
+
+// KeyStyle styles the attributes whose key matches a pattern.
+type KeyStyle struct {
+	// Key is the pattern matched against the attribute key, prefixed with
+	// its groups separated by dots, like "http.status".
+	// In the pattern, "*" matches any sequence of characters, including
+	// dots, and "?" matches any single character. Other characters match
+	// themselves, so that a pattern without wildcards matches a key exactly,
+	// and a pattern ending with "*" matches a prefix.
+	Key string
+
+	// KeyStyle is the style of the key. If empty, the theme's one is used.
+	KeyStyle ANSIMod
+
+	// ValueStyle is the style of the value. If empty, the theme's one is used.
+	ValueStyle ANSIMod
+
+	// Ranges style numeric values, including durations compared in
+	// nanoseconds, according to the first range containing them.
+	// Values outside of all ranges are styled with ValueStyle.
+	Ranges []ValueRange
+}
+
+// ValueRange is a range of numeric values, from Min included to Max excluded.
+type ValueRange struct {
+	Min, Max float64
+	Style    ANSIMod
+}
+
+// maxStackKeyLen is the length of the dotted keys built on the stack to be
+// matched against KeyStyle patterns.
+const maxStackKeyLen = 128
+
+// cloneKeyStyles returns a deep copy of rules, with their styles downgraded to depth.
+func cloneKeyStyles(rules []KeyStyle, depth ColorDepth) []KeyStyle {
+	rules = slices.Clone(rules)
+	for i := range rules {
+		r := &rules[i]
+		r.KeyStyle = downgradeStyle(r.KeyStyle, depth)
+		r.ValueStyle = downgradeStyle(r.ValueStyle, depth)
+		r.Ranges = slices.Clone(r.Ranges)
+		for j := range r.Ranges {
+			r.Ranges[j].Style = downgradeStyle(r.Ranges[j].Style, depth)
+		}
+	}
+	return rules
+}
+
+// matchKey returns the first KeyStyles rule matching the key prefixed with
+// groups, or nil if none does, and reports whether the key matches one of
+// the HashKeys patterns.
+func (e *encoder) matchKey(key string, groups []string) (rule *KeyStyle, hash bool) {
+	if !e.hasKeyStyles {
+		return nil, false
+	}
+	var arr [maxStackKeyLen]byte
+	k := appendDottedKey(arr[:0], key, groups)
+	for i := range e.opts.KeyStyles {
+		if r := &e.opts.KeyStyles[i]; matchGlob(r.Key, k) {
+			rule = r
+			break
+		}
+	}
+	for _, pattern := range e.opts.HashKeys {
+		if matchGlob(pattern, k) {
+			hash = true
+			break
+		}
+	}
+	return rule, hash
+}
+
+// appendDottedKey appends the key prefixed with groups, separated by dots.
+func appendDottedKey(dst []byte, key string, groups []string) []byte {
+	for _, g := range groups {
+		dst = append(dst, g...)
+		dst = append(dst, '.')
+	}
+	return append(dst, key...)
+}
+
+// keyRule returns the first KeyStyles rule matching the key prefixed with
+// groups, or nil if none does.
+func (e *encoder) keyRule(key string, groups []string) *KeyStyle {
+	rule, _ := e.matchKey(key, groups)
+	return rule
+}
+
+// keyStyle returns the style of keys matching r, def being the default one.
+func (r *KeyStyle) keyStyle(def ANSIMod) ANSIMod {
+	if r == nil || r.KeyStyle == "" {
+		return def
+	}
+	return r.KeyStyle
+}
+
+// valueStyle returns the style of value, for a key matching r,
+// def being the default one.
+func (r *KeyStyle) valueStyle(value slog.Value, def ANSIMod) ANSIMod {
+	if r == nil {
+		return def
+	}
+	if len(r.Ranges) > 0 {
+		if n, ok := numericValue(value); ok {
+			for _, rg := range r.Ranges {
+				if n >= rg.Min && n < rg.Max {
+					return rg.Style
+				}
+			}
+		}
+	}
+	if r.ValueStyle == "" {
+		return def
+	}
+	return r.ValueStyle
+}
+
+// numericValue returns value as a float64, if it has a numeric kind.
+func numericValue(value slog.Value) (float64, bool) {
+	switch value.Kind() {
+	case slog.KindInt64:
+		return float64(value.Int64()), true
+	case slog.KindUint64:
+		return float64(value.Uint64()), true
+	case slog.KindFloat64:
+		return value.Float64(), true
+	case slog.KindDuration:
+		return float64(value.Duration()), true
+	}
+	return 0, false
+}
+
+// matchGlob reports whether s matches the pattern, in which "*" matches any
+// sequence of characters and "?" any single character, decoded as UTF-8.
+// Wildcards in s are matched like any other character.
+func matchGlob(pattern string, s []byte) bool {
+	p, i := 0, 0
+	// Position of the last star in the pattern, and of the input it matched up to.
+	star, match := -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '?':
+			_, size := utf8.DecodeRune(s[i:])
+			p++
+			i += size
+		case p < len(pattern) && pattern[p] == '*':
+			star, match = p, i
+			p++
+		case p < len(pattern) && pattern[p] == s[i]:
+			p++
+			i++
+		case star >= 0:
+			// Backtrack, the last star matching one more character.
+			_, size := utf8.DecodeRune(s[match:])
+			match += size
+			p, i = star+1, match
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,103 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"user_id", "user_id", true},
+		{"user_id", "user_ids", false},
+		{"user_id", "user", false},
+		{"http.*", "http.status", true},
+		{"http.*", "http", false},
+		{"*.latency", "db.latency", true},
+		{"*.latency", "http.req.latency", true},
+		{"*.latency", "latency", false},
+		{"*", "", true},
+		{"*", "anything.at.all", true},
+		{"", "", true},
+		{"", "a", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*a*b*", "xxaxxbxx", true},
+		{"*a*b", "xxaxxbxxb", true},
+		{"*a*b", "xxaxxbxxc", false},
+		{"**x", "abx", true},
+		{"caf?", "café", true},
+		{"caf??", "café", false},
+		{"?.id", "é.id", true},
+		{"*?", "é", true},
+		{"*?é", "éé", true},
+		{"*", "*x", true},
+		{"a*", "a*b", true},
+		{"a??", "a?b", true},
+		{"a?b", "a?b", true},
+		{"*token", "*_token", true},
+		{"a*c", "a*b", false},
+	} {
+		AssertEqual(t, tc.expected, matchGlob(tc.pattern, []byte(tc.s)))
+	}
+}
+
+func TestHandler_KeyStyles(t *testing.T) {
+	magenta, yellow := ToANSICode(Magenta), ToANSICode(Yellow)
+	green, red := ToANSICode(Green), ToANSICode(Red)
+	key, value := NewDefaultTheme().AttrKey(), NewDefaultTheme().AttrValue()
+	rules := []KeyStyle{
+		{Key: "user_id", KeyStyle: magenta, ValueStyle: magenta},
+		{Key: "*.latency", ValueStyle: yellow},
+		{Key: "status", Ranges: []ValueRange{{Min: 200, Max: 300, Style: green}, {Min: 500, Max: 600, Style: red}}},
+		{Key: "user_*", KeyStyle: yellow},
+	}
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, ColorDepth: ColorDepth16, KeyStyles: rules, LevelNames: LevelNames{slog.LevelInfo: "I"}, Theme: NewTheme(NewDefaultTheme()).With(ElementLevelInfo, "").With(ElementMessage, "")})
+	rules[0].KeyStyle = red // Rules are copied
+
+	for _, tc := range []struct {
+		attr     slog.Attr
+		expected string
+	}{
+		{slog.Int("user_id", 12), fmt.Sprintf("%[1]suser_id=%[2]s%[1]s12%[2]s", magenta, ResetMod)},
+		{slog.String("user_name", "bob"), fmt.Sprintf("%[1]suser_name=%[2]sbob", yellow, ResetMod)},
+		{slog.Group("http", slog.Duration("latency", time.Second)), fmt.Sprintf("%[1]shttp.latency=%[2]s%[3]s1s%[2]s", key, ResetMod, yellow)},
+		{slog.Duration("latency", time.Second), fmt.Sprintf("%[1]slatency=%[2]s%[3]s1s%[2]s", key, ResetMod, ToANSICode(Blue))},
+		{slog.Int("status", 200), fmt.Sprintf("%[1]sstatus=%[2]s%[3]s200%[2]s", key, ResetMod, green)},
+		{slog.Uint64("status", 503), fmt.Sprintf("%[1]sstatus=%[2]s%[3]s503%[2]s", key, ResetMod, red)},
+		{slog.Float64("status", 404), fmt.Sprintf("%[1]sstatus=%[2]s%[3]s404%[2]s", key, ResetMod, ToANSICode(BrightBlue))},
+		{slog.String("status", "200"), fmt.Sprintf("%[1]sstatus=%[2]s200", key, ResetMod)},
+		{slog.String("other", "foo"), fmt.Sprintf("%[1]sother=%[2]sfoo", key, ResetMod)},
+	} {
+		buf.Reset()
+		rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+		rec.AddAttrs(tc.attr)
+		AssertNoError(t, h.Handle(context.Background(), rec))
+		AssertEqual(t, "I msg "+tc.expected+string(value)+"\n", buf.String())
+	}
+
+	// Rules also apply to attributes rendered in the block below records
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Multiline: true, KeyStyles: rules})
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	rec.AddAttrs(slog.String("user_name", "bob\nalice"))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, true, strings.Contains(buf.String(), string(yellow)+"user_name="+string(ResetMod)+"\n"))
+
+	// Long keys are matched too
+	long := strings.Repeat("a", 2*maxStackKeyLen)
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, KeyStyles: []KeyStyle{{Key: "*a", KeyStyle: magenta}}})
+	rec = slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	rec.AddAttrs(slog.Int(long, 1))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, true, strings.Contains(buf.String(), string(magenta)+long+"="))
+}
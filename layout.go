@@ -0,0 +1,161 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultLayout is the layout of records when HandlerOptions.Layout is empty.
+const DefaultLayout = "{time} {level} {source} > {prefix} {message} {attrs}"
+
+// layoutElement is an element of a record a layout placeholder stands for.
+type layoutElement int
+
+const (
+	layoutTime layoutElement = iota
+	layoutLevel
+	layoutSource
+	layoutPrefix
+	layoutMessage
+	layoutAttrs
+	numLayoutElements
+)
+
+var layoutElementNames = [numLayoutElements]string{
+	layoutTime:    "time",
+	layoutLevel:   "level",
+	layoutSource:  "source",
+	layoutPrefix:  "prefix",
+	layoutMessage: "message",
+	layoutAttrs:   "attrs",
+}
+
+// layoutSegment is a placeholder of a layout, along with the literal text
+// written around it.
+type layoutSegment struct {
+	element layoutElement
+	prefix  string // written before the element, if not omitted
+	suffix  string // written after the element, if not omitted
+	sep     string // written after the suffix, if another element follows
+}
+
+// layout is a compiled layout template.
+type layout struct {
+	head, tail string // written before the first element and after the last one
+	segments   []layoutSegment
+}
+
+// ValidateLayout reports whether layout is a valid HandlerOptions.Layout.
+func ValidateLayout(layout string) error {
+	_, err := parseLayout(layout)
+	return err
+}
+
+// parseLayout compiles the layout template s, or DefaultLayout if s is empty.
+//
+// The literal text between two placeholders is split around its whitespace:
+// the text before the first whitespace is a suffix of the first element, the
+// text after the last whitespace is a prefix of the second one, and the text
+// in between is a separator, written only between elements which are not
+// omitted. Literal text without whitespace is a separator.
+func parseLayout(s string) (layout, error) {
+	if s == "" {
+		s = DefaultLayout
+	}
+	var (
+		literals []string
+		elements []layoutElement
+		seen     [numLayoutElements]bool
+		literal  strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case strings.HasPrefix(s[i:], "{{"), strings.HasPrefix(s[i:], "}}"):
+			literal.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				return layout{}, fmt.Errorf("invalid layout %q: unclosed placeholder", s)
+			}
+			name := s[i+1 : i+end]
+			el := layoutElement(0)
+			for el < numLayoutElements && layoutElementNames[el] != name {
+				el++
+			}
+			if el == numLayoutElements {
+				return layout{}, fmt.Errorf("invalid layout %q: unknown placeholder %q", s, name)
+			}
+			if seen[el] {
+				return layout{}, fmt.Errorf("invalid layout %q: duplicate placeholder %q", s, name)
+			}
+			seen[el] = true
+			literals = append(literals, literal.String())
+			elements = append(elements, el)
+			literal.Reset()
+			i += end
+		case c == '}':
+			return layout{}, fmt.Errorf("invalid layout %q: unexpected %q", s, "}")
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	literals = append(literals, literal.String())
+
+	l := layout{segments: make([]layoutSegment, len(elements))}
+	if len(elements) == 0 {
+		l.head = literals[0]
+		return l, nil
+	}
+	l.head, l.segments[0].prefix = splitLastSpace(literals[0])
+	for i, el := range elements {
+		seg := &l.segments[i]
+		seg.element = el
+		if i == len(elements)-1 {
+			seg.suffix, l.tail = splitFirstSpace(literals[i+1])
+			break
+		}
+		lit := literals[i+1]
+		first, last := strings.IndexFunc(lit, unicode.IsSpace), strings.LastIndexFunc(lit, unicode.IsSpace)
+		if first < 0 {
+			seg.sep = lit
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(lit[last:])
+		seg.suffix, seg.sep, l.segments[i+1].prefix = lit[:first], lit[first:last+size], lit[last+size:]
+	}
+	return l, nil
+}
+
+// splitLastSpace splits s after its last whitespace.
+func splitLastSpace(s string) (string, string) {
+	i := strings.LastIndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return "", s
+	}
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return s[:i+size], s[i+size:]
+}
+
+// splitFirstSpace splits s before its first whitespace.
+func splitFirstSpace(s string) (string, string) {
+	if i := strings.IndexFunc(s, unicode.IsSpace); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// writeLiteral writes literal text of the layout, in the style of keys
+// unless it is only whitespace.
+func (e *encoder) writeLiteral(buf *buffer, s string) {
+	if s == "" {
+		return
+	}
+	if strings.TrimSpace(s) == "" {
+		buf.AppendString(s)
+		return
+	}
+	e.writeColoredString(buf, s, e.style(ElementAttrKey))
+}
@@ -0,0 +1,125 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLayout(t *testing.T) {
+	l, err := parseLayout("")
+	AssertNoError(t, err)
+	assertLayout(t, layout{segments: []layoutSegment{
+		{element: layoutTime, sep: " "},
+		{element: layoutLevel, sep: " "},
+		{element: layoutSource, sep: " > "},
+		{element: layoutPrefix, sep: " "},
+		{element: layoutMessage, sep: " "},
+		{element: layoutAttrs},
+	}}, l)
+
+	l, err = parseLayout(">> [{time}] {level}:{message} ({source}) {{x}}")
+	AssertNoError(t, err)
+	assertLayout(t, layout{head: ">> ", tail: " {x}", segments: []layoutSegment{
+		{element: layoutTime, prefix: "[", suffix: "]", sep: " "},
+		{element: layoutLevel, sep: ":"},
+		{element: layoutMessage, sep: " ", suffix: ""},
+		{element: layoutSource, prefix: "(", suffix: ")"},
+	}}, l)
+
+	l, err = parseLayout("static")
+	AssertNoError(t, err)
+	assertLayout(t, layout{head: "static"}, l)
+
+	for layout, msg := range map[string]string{
+		"{time} {lvl}":      `unknown placeholder "lvl"`,
+		"{message} {}":      `unknown placeholder ""`,
+		"{message} {attrs":  "unclosed placeholder",
+		"{message}} ":       `unexpected "}"`,
+		"{attrs} {attrs}":   `duplicate placeholder "attrs"`,
+		"{Message} {attrs}": `unknown placeholder "Message"`,
+	} {
+		err := ValidateLayout(layout)
+		AssertError(t, err)
+		AssertEqual(t, true, strings.Contains(err.Error(), msg))
+	}
+	AssertNoError(t, ValidateLayout("{message}"))
+}
+
+func assertLayout(t *testing.T, expected, actual layout) {
+	t.Helper()
+	AssertEqual(t, expected.head, actual.head)
+	AssertEqual(t, expected.tail, actual.tail)
+	AssertEqual(t, len(expected.segments), len(actual.segments))
+	for i := range expected.segments {
+		AssertEqual(t, expected.segments[i], actual.segments[i])
+	}
+}
+
+func TestHandler_Layout(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	buf := bytes.Buffer{}
+	ctx := []slog.Attr{slog.String("ctx", "x")}
+	rec := slog.NewRecord(now, slog.LevelInfo, "msg", 0)
+	srcRec := slog.NewRecord(now, slog.LevelInfo, "msg", pc())
+	noTime := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	src := fmt.Sprintf("layout_test.go:%d", srcLine)
+
+	for _, tc := range []struct {
+		layout   string
+		rec      slog.Record
+		opts     HandlerOptions
+		expected string
+	}{
+		{"{time} {level} [{source}] {message} {attrs}", srcRec, HandlerOptions{AddSource: true}, "2024-01-02 03:04:05 INF [" + src + "] msg ctx=x a=1\n"},
+		{"{time} {level} [{source}] {message} {attrs}", rec, HandlerOptions{AddSource: true}, "2024-01-02 03:04:05 INF msg ctx=x a=1\n"},
+		{"{level} {message} {attrs} ({source})", srcRec, HandlerOptions{AddSource: true}, "INF msg ctx=x a=1 (" + src + ")\n"},
+		{"{message}", rec, HandlerOptions{}, "msg\n"},
+		{"{message} | {attrs}", rec, HandlerOptions{}, "msg | ctx=x a=1\n"},
+		{"[{time}] {level}: {message}", noTime, HandlerOptions{}, "INF: msg\n"},
+		{"[{time}] {level}: {message}", rec, HandlerOptions{TimeFormat: time.Kitchen}, "[3:04AM] INF: msg\n"},
+		{"{level}|{prefix}|{message}", rec, HandlerOptions{PrefixKey: "a"}, "INF|[1]|msg\n"},
+		{"{level}|{prefix}|{message}", rec, HandlerOptions{PrefixKey: "b"}, "INF|msg\n"},
+		{"{level}|{prefix}|{message}", rec, HandlerOptions{PrefixKey: "b", PrefixWidth: 2}, "INF|    |msg\n"},
+		{"{{{level}}} {message}", rec, HandlerOptions{}, "{INF} msg\n"},
+		{"{time} {level} {message} {attrs}", rec, HandlerOptions{ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey {
+				return slog.Attr{}
+			}
+			return a
+		}}, "2024-01-02 03:04:05 msg ctx=x a=1\n"},
+	} {
+		opts := tc.opts
+		opts.Layout, opts.NoColor = tc.layout, true
+		AssertEqual(t, tc.expected, logRecord(t, &buf, NewHandler(&buf, &opts).WithAttrs(ctx), tc.rec, slog.Int("a", 1)))
+	}
+
+	// Elements left out are not written in the block either
+	opts := &HandlerOptions{Layout: "{level} {message} {attrs}", Multiline: true, NoColor: true}
+	multiline := slog.NewRecord(now, slog.LevelInfo, "msg\nline", 0)
+	AssertEqual(t, "INF msg ctx=x\n  │ line\n  │ a=\n  │   b\n  │   c\n", logRecord(t, &buf, NewHandler(&buf, opts).WithAttrs(ctx), multiline, slog.String("a", "b\nc")))
+	opts = &HandlerOptions{Layout: "{level} {message}", Multiline: true, NoColor: true}
+	AssertEqual(t, "INF msg\n  │ line\n", logRecord(t, &buf, NewHandler(&buf, opts).WithAttrs(ctx), multiline, slog.String("a", "b\nc")))
+
+	// Literal text is styled like keys
+	h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Layout: "{level} | {message}", Theme: NewTheme(NewDefaultTheme()).With(ElementLevelInfo, "").With(ElementMessage, "")})
+	AssertEqual(t, fmt.Sprintf("INF%s | %smsg\n", NewDefaultTheme().AttrKey(), ResetMod), logRecord(t, &buf, h, rec))
+
+	defer func() {
+		AssertNotEqual(t, nil, recover())
+	}()
+	NewHandler(&buf, &HandlerOptions{Layout: "{nope}"})
+}
+
+var srcLine int
+
+func pc() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	_, _, srcLine, _ = runtime.Caller(1)
+	return pcs[0]
+}
@@ -0,0 +1,136 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LevelNames maps levels to the labels they are printed with.
+// A level without a label is printed as the label of the closest lower
+// level having one, followed by the offset from it, like "INF+2".
+// Levels lower than all labeled levels use the lowest label, like "DBG-4".
+type LevelNames map[slog.Level]string
+
+// ShortLevelNames returns three-letter labels for the standard levels.
+// They are the default ones.
+func ShortLevelNames() LevelNames {
+	return LevelNames{
+		slog.LevelDebug: "DBG",
+		slog.LevelInfo:  "INF",
+		slog.LevelWarn:  "WRN",
+		slog.LevelError: "ERR",
+	}
+}
+
+// FullLevelNames returns full-word labels for the standard levels.
+func FullLevelNames() LevelNames {
+	return LevelNames{
+		slog.LevelDebug: "DEBUG",
+		slog.LevelInfo:  "INFO",
+		slog.LevelWarn:  "WARN",
+		slog.LevelError: "ERROR",
+	}
+}
+
+// LetterLevelNames returns single-letter labels for the standard levels.
+func LetterLevelNames() LevelNames {
+	return LevelNames{
+		slog.LevelDebug: "D",
+		slog.LevelInfo:  "I",
+		slog.LevelWarn:  "W",
+		slog.LevelError: "E",
+	}
+}
+
+// Parse returns the level named s, which is either a label, case-insensitively,
+// optionally followed by an offset like "+2", the name of a standard level
+// as accepted by slog.Level.UnmarshalText, or an integer.
+// Labels may themselves contain "+" or "-", like "SEV-1".
+func (n LevelNames) Parse(s string) (slog.Level, error) {
+	s = strings.TrimSpace(s)
+	if l, ok := n.lookup(s); ok {
+		return l, nil
+	}
+	if i := strings.LastIndexAny(s, "+-"); i > 0 {
+		if off, err := strconv.Atoi(s[i:]); err == nil {
+			if l, ok := n.lookup(s[:i]); ok {
+				return l + slog.Level(off), nil
+			}
+		}
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return slog.Level(i), nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown level %q", s)
+	}
+	return l, nil
+}
+
+// lookup returns the level labeled name, case-insensitively.
+func (n LevelNames) lookup(name string) (slog.Level, bool) {
+	for l, label := range n {
+		if strings.EqualFold(label, name) {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// levelLabel is a labeled level.
+type levelLabel struct {
+	level slog.Level
+	label string
+}
+
+// levelLabels holds level labels sorted by level, for fast lookups.
+type levelLabels struct {
+	labels []levelLabel
+	width  int // width of the longest label
+}
+
+func newLevelLabels(names LevelNames) levelLabels {
+	if len(names) == 0 {
+		names = ShortLevelNames()
+	}
+	ll := levelLabels{}
+	for l, label := range names {
+		ll.labels = append(ll.labels, levelLabel{l, label})
+		ll.width = max(ll.width, utf8.RuneCountInString(label))
+	}
+	slices.SortFunc(ll.labels, func(a, b levelLabel) int { return int(a.level - b.level) })
+	return ll
+}
+
+// lookup returns the label for l, and the offset of l from the labeled level.
+func (ll levelLabels) lookup(l slog.Level) (string, int) {
+	found := ll.labels[0]
+	for _, lbl := range ll.labels[1:] {
+		if lbl.level > l {
+			break
+		}
+		found = lbl
+	}
+	return found.label, int(l - found.level)
+}
+
+// offsetWidth returns the number of characters needed to print a non-zero
+// level offset with its sign.
+func offsetWidth(offset int) int {
+	if offset == 0 {
+		return 0
+	}
+	n := 1
+	if offset < 0 {
+		offset = -offset
+	}
+	for ; offset > 0; offset /= 10 {
+		n++
+	}
+	return n
+}
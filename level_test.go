@@ -0,0 +1,74 @@
+package console
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestLevelNames_Parse(t *testing.T) {
+	names := ShortLevelNames()
+	names[slog.Level(-8)] = "TRC"
+	names[slog.Level(12)] = "FTL"
+	for s, expected := range map[string]slog.Level{
+		"INF":    slog.LevelInfo,
+		"inf":    slog.LevelInfo,
+		" wrn ":  slog.LevelWarn,
+		"TRC":    slog.Level(-8),
+		"ftl":    slog.Level(12),
+		"FTL+2":  slog.Level(14),
+		"DBG-1":  slog.LevelDebug - 1,
+		"ERROR":  slog.LevelError,
+		"info+1": slog.LevelInfo + 1,
+		"-8":     slog.Level(-8),
+		"3":      slog.Level(3),
+	} {
+		l, err := names.Parse(s)
+		AssertNoError(t, err)
+		AssertEqual(t, expected, l)
+	}
+
+	for _, s := range []string{"", "FOO", "INF+x", "+3x"} {
+		_, err := names.Parse(s)
+		AssertError(t, err)
+	}
+
+	// Labels containing offset signs
+	names = LevelNames{slog.LevelError: "SEV-1", slog.LevelWarn: "SEV-2", slog.LevelInfo: "SEV+"}
+	for s, expected := range map[string]slog.Level{
+		"SEV-1":   slog.LevelError,
+		"sev-2":   slog.LevelWarn,
+		"SEV-1+2": slog.LevelError + 2,
+		"SEV-2-1": slog.LevelWarn - 1,
+		"SEV+":    slog.LevelInfo,
+		"SEV++1":  slog.LevelInfo + 1,
+	} {
+		l, err := names.Parse(s)
+		AssertNoError(t, err)
+		AssertEqual(t, expected, l)
+	}
+}
+
+func TestLevelLabels(t *testing.T) {
+	ll := newLevelLabels(LevelNames{
+		slog.Level(-8): "TRACE",
+		slog.LevelInfo: "INFO",
+		slog.Level(12): "FATAL",
+	})
+	AssertEqual(t, 5, ll.width)
+	for l, expected := range map[slog.Level]struct {
+		label  string
+		offset int
+	}{
+		slog.Level(-10): {"TRACE", -2},
+		slog.Level(-8):  {"TRACE", 0},
+		slog.LevelDebug: {"TRACE", 4},
+		slog.LevelInfo:  {"INFO", 0},
+		slog.LevelError: {"INFO", 8},
+		slog.Level(12):  {"FATAL", 0},
+		slog.Level(13):  {"FATAL", 1},
+	} {
+		label, offset := ll.lookup(l)
+		AssertEqual(t, expected.label, label)
+		AssertEqual(t, expected.offset, offset)
+	}
+}
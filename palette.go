@@ -0,0 +1,191 @@
+package console
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// palette16 holds the RGB values of the 16 basic colors, as in xterm.
+var palette16 = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels holds the component values of the 6x6x6 color cube
+// of the 256 colors palette.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+// parseHex parses a color written as "#rrggbb" or "#rgb".
+func parseHex(s string) (r, g, b uint8, err error) {
+	h := strings.TrimPrefix(s, "#")
+	if len(h) == 3 {
+		h = string([]byte{h[0], h[0], h[1], h[1], h[2], h[2]})
+	}
+	v, perr := strconv.ParseUint(h, 16, 32)
+	if len(h) != 6 || perr != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hex color %q", s)
+	}
+	return uint8(v >> 16), uint8(v >> 8), uint8(v), nil
+}
+
+// sqDist returns the squared euclidean distance between two colors.
+func sqDist(r1, g1, b1, r2, g2, b2 uint8) int {
+	dr, dg, db := int(r1)-int(r2), int(g1)-int(g2), int(b1)-int(b2)
+	return dr*dr + dg*dg + db*db
+}
+
+// rgbTo256 returns the nearest color of the 256 colors palette,
+// excluding the 16 basic ones whose values depend on the terminal.
+func rgbTo256(r, g, b uint8) uint8 {
+	cubeIndex := func(v uint8) int {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		default:
+			return (int(v) - 35) / 40
+		}
+	}
+	ri, gi, bi := cubeIndex(r), cubeIndex(g), cubeIndex(b)
+	cube := uint8(16 + 36*ri + 6*gi + bi)
+	cubeDist := sqDist(r, g, b, cubeLevels[ri], cubeLevels[gi], cubeLevels[bi])
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	grayIndex := min(max((avg-3)/10, 0), 23)
+	gray := uint8(8 + 10*grayIndex)
+	if sqDist(r, g, b, gray, gray, gray) < cubeDist {
+		return uint8(232 + grayIndex)
+	}
+	return cube
+}
+
+// color256ToRGB returns the RGB values of the color n of the 256 colors palette.
+func color256ToRGB(n uint8) (r, g, b uint8) {
+	switch {
+	case n < 16:
+		c := palette16[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		v := 8 + 10*(n-232)
+		return v, v, v
+	}
+}
+
+// rgbTo16 returns the index of the nearest basic color.
+func rgbTo16(r, g, b uint8) int {
+	best, bestDist := 0, -1
+	for i, c := range palette16 {
+		if d := sqDist(r, g, b, c[0], c[1], c[2]); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// basicColorCode returns the SGR parameter setting the foreground, or the
+// background if bg is true, to the basic color of index i.
+func basicColorCode(i int, bg bool) int {
+	code := 30 + i
+	if i >= 8 {
+		code = 90 + i - 8
+	}
+	if bg {
+		code += 10
+	}
+	return code
+}
+
+// downgradeStyle returns style with its 256 and 24-bit colors replaced by
+// the nearest ones supported with the given depth.
+// It doesn't allocate if style has no such color, or if depth supports them.
+func downgradeStyle(style ANSIMod, depth ColorDepth) ANSIMod {
+	s := string(style)
+	if depth >= ColorDepthTrueColor || !strings.Contains(s, "8;") {
+		return style
+	}
+	var out []byte
+	for {
+		i := strings.Index(s, "\x1b[")
+		j := strings.IndexByte(s[max(i, 0):], 'm')
+		if i < 0 || j < 0 {
+			out = append(out, s...)
+			break
+		}
+		j += i
+		out = append(out, s[:i+2]...)
+		out = appendDowngradedParams(out, s[i+2:j], depth)
+		out = append(out, 'm')
+		s = s[j+1:]
+	}
+	return ANSIMod(out)
+}
+
+// appendDowngradedParams appends the SGR parameters params, with their
+// extended colors downgraded to depth.
+func appendDowngradedParams(out []byte, params string, depth ColorDepth) []byte {
+	ps := strings.Split(params, ";")
+	num := func(i int) (uint8, bool) {
+		if i >= len(ps) {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(ps[i], 10, 8)
+		return uint8(n), err == nil
+	}
+	sep := false
+	appendCode := func(codes ...int) {
+		for _, c := range codes {
+			if sep {
+				out = append(out, ';')
+			}
+			out = strconv.AppendInt(out, int64(c), 10)
+			sep = true
+		}
+	}
+	for i := 0; i < len(ps); i++ {
+		if (ps[i] == "38" || ps[i] == "48") && i+1 < len(ps) {
+			bg := ps[i] == "48"
+			base, _ := strconv.Atoi(ps[i])
+			switch ps[i+1] {
+			case "5":
+				if n, ok := num(i + 2); ok {
+					i += 2
+					switch {
+					case depth >= ColorDepth256:
+						appendCode(base, 5, int(n))
+					case n < 16:
+						appendCode(basicColorCode(int(n), bg))
+					default:
+						appendCode(basicColorCode(rgbTo16(color256ToRGB(n)), bg))
+					}
+					continue
+				}
+			case "2":
+				r, rok := num(i + 2)
+				g, gok := num(i + 3)
+				b, bok := num(i + 4)
+				if rok && gok && bok {
+					i += 4
+					if depth >= ColorDepth256 {
+						appendCode(base, 5, int(rgbTo256(r, g, b)))
+					} else {
+						appendCode(basicColorCode(rgbTo16(r, g, b), bg))
+					}
+					continue
+				}
+			}
+		}
+		if sep {
+			out = append(out, ';')
+		}
+		out = append(out, ps[i]...)
+		sep = true
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestColorConstructors(t *testing.T) {
+	AssertEqual(t, ANSIMod("\x1b[38;5;208m"), Color256(208))
+	AssertEqual(t, ANSIMod("\x1b[48;5;236m"), BgColor256(236))
+	AssertEqual(t, ANSIMod("\x1b[38;2;255;136;0m"), RGB(255, 136, 0))
+	AssertEqual(t, ANSIMod("\x1b[48;2;46;52;64m"), BgRGB(46, 52, 64))
+	AssertEqual(t, ANSIMod("\x1b[41m"), ToANSICode(BgRed))
+	AssertEqual(t, ANSIMod("\x1b[107m"), ToANSICode(BgWhite))
+
+	for s, expected := range map[string]ANSIMod{
+		"#ff8800": RGB(255, 136, 0),
+		"FF8800":  RGB(255, 136, 0),
+		"#f80":    RGB(255, 136, 0),
+		"#2e3440": RGB(46, 52, 64),
+	} {
+		mod, err := Hex(s)
+		AssertNoError(t, err)
+		AssertEqual(t, expected, mod)
+	}
+	mod, err := BgHex("#2e3440")
+	AssertNoError(t, err)
+	AssertEqual(t, BgRGB(46, 52, 64), mod)
+
+	for _, s := range []string{"", "#", "#ff88", "#ff880", "#gg8800", "#ff8800ff", "+f8800"} {
+		_, err := Hex(s)
+		AssertError(t, err)
+	}
+}
+
+func TestDowngradeStyle(t *testing.T) {
+	tests := []struct {
+		style    ANSIMod
+		depth    ColorDepth
+		expected ANSIMod
+	}{
+		{RGB(255, 136, 0), ColorDepthTrueColor, RGB(255, 136, 0)},
+		{RGB(255, 136, 0), ColorDepth256, Color256(208)},
+		{RGB(255, 136, 0), ColorDepth16, ToANSICode(Yellow)},
+		{BgRGB(255, 136, 0), ColorDepth16, ToANSICode(BgYellow)},
+		{RGB(128, 128, 128), ColorDepth256, Color256(244)},
+		{RGB(128, 128, 128), ColorDepth16, ToANSICode(BrightBlack)},
+		{Color256(196), ColorDepth256, Color256(196)},
+		{Color256(196), ColorDepth16, ToANSICode(BrightRed)},
+		{Color256(4), ColorDepth16, ToANSICode(Blue)},
+		{BgColor256(12), ColorDepth16, ToANSICode(BgBrightBlue)},
+		{ToANSICode(Bold, Red), ColorDepth16, ToANSICode(Bold, Red)},
+		{ToANSICode(), ColorDepth16, ToANSICode()},
+		{"\x1b[1;38;2;255;136;0;48;5;236m", ColorDepth256, "\x1b[1;38;5;208;48;5;236m"},
+		{"\x1b[1;38;2;255;136;0;48;5;236m", ColorDepth16, "\x1b[1;33;40m"},
+		{ToANSICode(Bold) + RGB(255, 0, 0) + BgColor256(236), ColorDepth16, "\x1b[1m\x1b[91m\x1b[40m"},
+		// Invalid sequences are left untouched
+		{"\x1b[38;2;255m", ColorDepth16, "\x1b[38;2;255m"},
+		{"\x1b[38;5;300m", ColorDepth16, "\x1b[38;5;300m"},
+		{"\x1b[38;5;1", ColorDepth16, "\x1b[38;5;1"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%q/%s", tc.style, tc.depth), func(t *testing.T) {
+			AssertEqual(t, tc.expected, downgradeStyle(tc.style, tc.depth))
+		})
+	}
+}
+
+func TestRGBTo256(t *testing.T) {
+	for n := 16; n < 256; n++ {
+		AssertEqual(t, uint8(n), rgbTo256(color256ToRGB(uint8(n))))
+	}
+	for i, c := range palette16 {
+		AssertEqual(t, i, rgbTo16(c[0], c[1], c[2]))
+	}
+}
+
+func TestColorDepth_Resolve(t *testing.T) {
+	for _, tc := range []struct {
+		colorterm, term string
+		expected        ColorDepth
+	}{
+		{"", "", ColorDepth16},
+		{"", "xterm", ColorDepth16},
+		{"", "xterm-256color", ColorDepth256},
+		{"", "xterm-direct", ColorDepthTrueColor},
+		{"truecolor", "xterm-256color", ColorDepthTrueColor},
+		{"24bit", "", ColorDepthTrueColor},
+		{"yes", "screen-256color", ColorDepth256},
+	} {
+		t.Setenv("COLORTERM", tc.colorterm)
+		t.Setenv("TERM", tc.term)
+		AssertEqual(t, tc.expected, ColorDepthAuto.resolve())
+		AssertEqual(t, ColorDepth256, ColorDepth256.resolve())
+	}
+}
+
+func TestHandler_ColorDepth(t *testing.T) {
+	theme := NewTheme(nil).
+		With(ElementMessage, RGB(255, 136, 0)).
+		With(ElementLevelInfo, BgColor256(236)).
+		WithLevel(12, Color256(196))
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+	fatal := slog.NewRecord(time.Time{}, 12, "foobar", 0)
+
+	for depth, expected := range map[ColorDepth]string{
+		ColorDepthTrueColor: fmt.Sprintf("%[1]sINF%[2]s %[3]sfoobar%[2]s\n%[4]sINF+12%[2]s %[3]sfoobar%[2]s\n", BgColor256(236), ResetMod, RGB(255, 136, 0), Color256(196)),
+		ColorDepth256:       fmt.Sprintf("%[1]sINF%[2]s %[3]sfoobar%[2]s\n%[4]sINF+12%[2]s %[3]sfoobar%[2]s\n", BgColor256(236), ResetMod, Color256(208), Color256(196)),
+		ColorDepth16:        fmt.Sprintf("%[1]sINF%[2]s %[3]sfoobar%[2]s\n%[4]sINF+12%[2]s %[3]sfoobar%[2]s\n", ToANSICode(BgBlack), ResetMod, ToANSICode(Yellow), ToANSICode(BrightRed)),
+	} {
+		buf := bytes.Buffer{}
+		h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, ColorDepth: depth, Theme: theme, LevelNames: LevelNames{slog.LevelInfo: "INF"}})
+		AssertNoError(t, h.Handle(context.Background(), rec))
+		AssertNoError(t, h.Handle(context.Background(), fatal))
+		AssertEqual(t, expected, buf.String())
+	}
+
+	// Themes styling levels themselves are downgraded too
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, ColorDepth: ColorDepth16, Theme: AdaptTheme(oldTheme{NewDefaultTheme()}), LevelNames: LevelNames{slog.LevelInfo: "INF"}})
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, true, bytes.HasPrefix(buf.Bytes(), []byte(ToANSICode(Blue)+"INF")))
+}
@@ -0,0 +1,76 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+)
+
+// maxLogValuerDepth bounds the number of LogValue calls when resolving a value,
+// like slog.Value.Resolve does.
+const maxLogValuerDepth = 100
+
+// panicError is the value of attributes whose rendering panicked.
+type panicError struct {
+	recovered any
+}
+
+func (p panicError) Error() string {
+	return fmt.Sprintf("!PANIC(%v)", p.recovered)
+}
+
+// isNilPointer reports whether v is a nil pointer,
+// which is likely the cause of a panic in one of its methods.
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// resolve is like slog.Value.Resolve, but a LogValue method panicking with a
+// nil pointer receiver resolves to "<nil>", and other panics to a panicError.
+func resolve(v slog.Value) (rv slog.Value) {
+	if v.Kind() != slog.KindLogValuer {
+		return v
+	}
+	var lv slog.LogValuer
+	defer func() {
+		if r := recover(); r != nil {
+			if isNilPointer(lv) {
+				rv = slog.StringValue("<nil>")
+			} else {
+				rv = slog.AnyValue(panicError{r})
+			}
+		}
+	}()
+	for i := 0; i < maxLogValuerDepth && v.Kind() == slog.KindLogValuer; i++ {
+		lv = v.LogValuer()
+		v = lv.LogValue()
+	}
+	return v.Resolve()
+}
+
+// recoverText recovers from a panic raised while getting the text of v,
+// replacing s with "<nil>" in the nil style if v is a nil pointer, or with a panic message
+// in the error style otherwise.
+func (e *encoder) recoverText(v any, s *string, style *ANSIMod) {
+	if r := recover(); r != nil {
+		if isNilPointer(v) {
+			*s, *style = "<nil>", e.style(ElementAttrValueNil)
+		} else {
+			*s, *style = panicError{r}.Error(), e.style(ElementAttrValueError)
+		}
+	}
+}
+
+// tryWrite calls write and returns its result. If write panics, what it
+// wrote to block is discarded and tryWrite returns false.
+func tryWrite(block *buffer, write func() bool) (ok bool) {
+	n := block.Len()
+	defer func() {
+		if r := recover(); r != nil {
+			*block = (*block)[:n]
+			ok = false
+		}
+	}()
+	return write()
+}
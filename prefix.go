@@ -0,0 +1,141 @@
+package console
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// prefixKeyIn returns the PrefixKey option relative to groups, and reports
+// whether it is within them.
+func (e *encoder) prefixKeyIn(groups []string) (string, bool) {
+	p := e.opts.PrefixKey
+	for _, g := range groups {
+		if !strings.HasPrefix(p, g) || len(p) == len(g) || p[len(g)] != '.' {
+			return "", false
+		}
+		p = p[len(g)+1:]
+	}
+	return p, true
+}
+
+// isPrefixKey reports whether key, prefixed with groups, is the PrefixKey option.
+func (e *encoder) isPrefixKey(key string, groups []string) bool {
+	p, ok := e.prefixKeyIn(groups)
+	return ok && p == key
+}
+
+// isPrefixGroup reports whether the group key, prefixed with groups, may hold
+// the PrefixKey attribute. Groups without a key are inlined, so they may.
+func (e *encoder) isPrefixGroup(key string, groups []string) bool {
+	p, ok := e.prefixKeyIn(groups)
+	return ok && (key == "" || strings.HasPrefix(p, key) && len(p) > len(key) && p[len(key)] == '.')
+}
+
+// findPrefix looks for the PrefixKey attribute in a, prefixed with groups,
+// and in its nested groups. If there is one, it returns the value of the last
+// one as replacePrefix does, and reports true as found.
+func (e *encoder) findPrefix(a slog.Attr, groups []string) (value slog.Value, ok, found bool) {
+	if e.isPrefixKey(a.Key, groups) {
+		value, ok = e.replacePrefix(a, groups)
+		return value, ok, true
+	}
+	if !e.isPrefixGroup(a.Key, groups) {
+		return slog.Value{}, false, false
+	}
+	a.Value = resolve(a.Value)
+	if a.Value.Kind() != slog.KindGroup {
+		return slog.Value{}, false, false
+	}
+	if a.Key != "" {
+		groups = append(groups, a.Key)
+	}
+	for _, attr := range a.Value.Group() {
+		if v, o, f := e.findPrefix(attr, groups); f {
+			value, ok, found = v, o, true
+		}
+	}
+	return value, ok, found
+}
+
+// redactedPrefix wraps the value of a PrefixKey attribute which is redacted.
+type redactedPrefix struct{ value slog.Value }
+
+// replacePrefix returns the resolved value of the PrefixKey attribute a,
+// after the ReplaceAttr option, wrapped in a redactedPrefix if the Redact
+// option hides it. It reports false if ReplaceAttr discards it.
+func (e *encoder) replacePrefix(a slog.Attr, groups []string) (slog.Value, bool) {
+	a.Value = resolve(a.Value)
+	if rep := e.opts.ReplaceAttr; rep != nil && a.Value.Kind() != slog.KindGroup {
+		a = rep(slices.Clone(groups), a)
+		a.Value = resolve(a.Value)
+	}
+	if a.Equal(slog.Attr{}) || a.Value.Kind() == slog.KindGroup {
+		return slog.Value{}, false
+	}
+	if e.hasRedact && e.redactKey(a.Key, groups) {
+		return slog.AnyValue(redactedPrefix{a.Value}), true
+	}
+	if len(e.opts.Redact.Detectors) > 0 && !isScalar(a.Value.Kind()) {
+		if s, _, _ := e.textValue(a.Value); e.redactValue(s) {
+			return slog.AnyValue(redactedPrefix{a.Value}), true
+		}
+	}
+	return a.Value, true
+}
+
+// writePrefix writes the value of the PrefixKey attribute between brackets,
+// padded or truncated to the PrefixWidth option, or a blank column if ok is
+// false. A redacted value is written as "[REDACTED]", in the style of redacted
+// attributes. It reports whether anything was written.
+func (e *encoder) writePrefix(buf *buffer, value slog.Value, ok bool) bool {
+	width := e.opts.PrefixWidth
+	if !ok {
+		if width == 0 {
+			return false
+		}
+		for i := 0; i < width+2; i++ { // including brackets
+			buf.AppendByte(' ')
+		}
+		return true
+	}
+	redacted, isRedacted := anyValue(value).(redactedPrefix)
+	var s string
+	var isText bool
+	style := e.style(ElementAttrValueNil)
+	if !isRedacted {
+		s, _, isText = e.textValue(value)
+		style = e.style(ElementPrefix)
+		if e.opts.PrefixHash {
+			style = e.hashStyle(value, s, isText)
+		}
+	}
+	n := 0
+	e.withColor(buf, style, func() {
+		buf.AppendByte('[')
+		start := buf.Len()
+		if isRedacted {
+			e.appendRedacted(buf, redacted.value)
+		} else if isText {
+			e.appendSanitized(buf, s)
+		} else {
+			e.writeValue(buf, value, "")
+		}
+		n = utf8.RuneCount((*buf)[start:])
+		if width > 0 && n > width {
+			end := start
+			for i := 0; i < width; i++ {
+				_, size := utf8.DecodeRune((*buf)[end:])
+				end += size
+			}
+			*buf = (*buf)[:end]
+			n = width
+		}
+		buf.AppendByte(']')
+	})
+	for ; n < width; n++ {
+		buf.AppendByte(' ')
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEncoder_IsPrefixKey(t *testing.T) {
+	e := encoder{opts: HandlerOptions{PrefixKey: "app.db.component"}}
+	for _, tc := range []struct {
+		key      string
+		groups   []string
+		expected bool
+	}{
+		{"app.db.component", nil, true},
+		{"component", []string{"app", "db"}, true},
+		{"db.component", []string{"app"}, true},
+		{"component", []string{"app"}, false},
+		{"component", []string{"app", "db", "x"}, false},
+		{"component", []string{"ap", "db"}, false},
+		{"component", []string{"app.db"}, true},
+		{"comp", []string{"app", "db"}, false},
+	} {
+		AssertEqual(t, tc.expected, e.isPrefixKey(tc.key, tc.groups))
+	}
+	AssertEqual(t, true, e.isPrefixGroup("app", nil))
+	AssertEqual(t, true, e.isPrefixGroup("app.db", nil))
+	AssertEqual(t, true, e.isPrefixGroup("db", []string{"app"}))
+	AssertEqual(t, true, e.isPrefixGroup("", []string{"app"}))
+	AssertEqual(t, false, e.isPrefixGroup("ap", nil))
+	AssertEqual(t, false, e.isPrefixGroup("app.db.component", nil))
+	AssertEqual(t, false, e.isPrefixGroup("", []string{"db"}))
+}
+
+func TestHandler_Prefix(t *testing.T) {
+	buf := bytes.Buffer{}
+	opts := &HandlerOptions{NoColor: true, PrefixKey: "logger", LevelNames: LevelNames{slog.LevelInfo: "I"}}
+	h := NewHandler(&buf, opts)
+
+	AssertEqual(t, "I msg\n", logAttrs(t, &buf, h, "msg"))
+	AssertEqual(t, "I [db] msg foo=bar\n", logAttrs(t, &buf, h, "msg", slog.String("logger", "db"), slog.String("foo", "bar")))
+	AssertEqual(t, "I [12] msg\n", logAttrs(t, &buf, h, "msg", slog.Int("logger", 12)))
+
+	// From the context, overridden by the record
+	db := h.WithAttrs([]slog.Attr{slog.String("foo", "bar"), slog.String("logger", "db")})
+	AssertEqual(t, "I [db] msg foo=bar\n", logAttrs(t, &buf, db, "msg"))
+	AssertEqual(t, "I [http] msg foo=bar\n", logAttrs(t, &buf, db, "msg", slog.String("logger", "http")))
+	AssertEqual(t, "I [db] msg foo=bar g.x=1\n", logAttrs(t, &buf, db.WithGroup("g"), "msg", slog.Int("x", 1)))
+	AssertEqual(t, "I [db] msg foo=bar g.logger=x\n", logAttrs(t, &buf, db.WithGroup("g"), "msg", slog.String("logger", "x")))
+
+	// Within groups
+	opts.PrefixKey = "app.logger"
+	h = NewHandler(&buf, opts)
+	AssertEqual(t, "I msg logger=x\n", logAttrs(t, &buf, h, "msg", slog.String("logger", "x")))
+	AssertEqual(t, "I [db] msg\n", logAttrs(t, &buf, h.WithGroup("app"), "msg", slog.String("logger", "db")))
+	AssertEqual(t, "I [db] msg\n", logAttrs(t, &buf, h.WithGroup("app").WithAttrs([]slog.Attr{slog.String("logger", "db")}), "msg"))
+	// and within group attributes, from the record and from WithAttrs
+	AssertEqual(t, "I [db] msg app.x=1\n", logAttrs(t, &buf, h, "msg", slog.Group("app", slog.String("logger", "db"), slog.Int("x", 1))))
+	AssertEqual(t, "I [db] msg\n", logAttrs(t, &buf, h, "msg", slog.Group("", slog.Group("app", slog.String("logger", "db")))))
+	AssertEqual(t, "I [db] msg other.logger=x\n", logAttrs(t, &buf, h, "msg", slog.Group("app", slog.String("logger", "db")), slog.Group("other", slog.String("logger", "x"))))
+	AssertEqual(t, "I [db] msg app.x=1\n", logAttrs(t, &buf, h.WithAttrs([]slog.Attr{slog.Group("app", slog.String("logger", "db"), slog.Int("x", 1))}), "msg"))
+	AssertEqual(t, "I [http] msg\n", logAttrs(t, &buf, h.WithGroup("app").WithAttrs([]slog.Attr{slog.String("logger", "db")}), "msg", slog.String("logger", "http")))
+
+	// Fixed width
+	opts.PrefixKey, opts.PrefixWidth = "logger", 4
+	h = NewHandler(&buf, opts)
+	AssertEqual(t, "I [db]   msg\n", logAttrs(t, &buf, h, "msg", slog.String("logger", "db")))
+	AssertEqual(t, "I [daté] msg\n", logAttrs(t, &buf, h, "msg", slog.String("logger", "datébase")))
+	AssertEqual(t, "I [\\x1b] msg\n", logAttrs(t, &buf, h, "msg", slog.String("logger", "\x1b[31m")))
+	AssertEqual(t, "I        msg\n", logAttrs(t, &buf, h, "msg"))
+
+	// ReplaceAttr
+	opts.ReplaceAttr = func(_ []string, a slog.Attr) slog.Attr {
+		if a.Key == "logger" && a.Value.String() == "drop" {
+			return slog.Attr{}
+		}
+		if a.Key == "logger" {
+			return slog.String(a.Key, strings.ToUpper(a.Value.String()))
+		}
+		return a
+	}
+	h = NewHandler(&buf, opts)
+	AssertEqual(t, "I [DB]   msg\n", logAttrs(t, &buf, h, "msg", slog.String("logger", "db")))
+	AssertEqual(t, "I        msg\n", logAttrs(t, &buf, h, "msg", slog.String("logger", "drop")))
+}
+
+func TestHandler_PrefixStyle(t *testing.T) {
+	buf := bytes.Buffer{}
+	opts := &HandlerOptions{ColorMode: ColorAlways, ColorDepth: ColorDepth16, PrefixKey: "logger", PrefixWidth: 4, LevelNames: LevelNames{slog.LevelInfo: "I"}, Theme: NewTheme(NewDefaultTheme()).With(ElementLevelInfo, "").With(ElementMessage, "")}
+	out := logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("logger", "db"))
+	AssertEqual(t, fmt.Sprintf("I %s[db]%s   msg\n", ToANSICode(Bold, Blue), ResetMod), out)
+
+	opts.PrefixHash = true
+	h := NewHandler(&buf, opts)
+	AssertNotEqual(t, 0, len(h.enc.hashPalette))
+	styles := map[ANSIMod]bool{}
+	for _, name := range []string{"db", "http", "cache", "queue", "auth", "mail", "cron", "grpc"} {
+		out := strings.TrimPrefix(logAttrs(t, &buf, h, "msg", slog.String("logger", name)), "I ")
+		style := ANSIMod(out[:strings.IndexByte(out, 'm')+1])
+		AssertEqual(t, true, slices.Contains(h.enc.hashPalette, style))
+		styles[style] = true
+	}
+	AssertGreaterOrEqual(t, 2, len(styles))
+}
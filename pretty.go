@@ -0,0 +1,307 @@
+package console
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+)
+
+const (
+	// defaultPrettyMaxDepth is the nesting depth pretty values are printed up
+	// to when the PrettyMaxDepth option is zero.
+	defaultPrettyMaxDepth = 5
+	// defaultPrettyMaxItems is the number of elements printed in containers
+	// when the PrettyMaxItems option is zero.
+	defaultPrettyMaxItems = 32
+)
+
+// isPretty reports whether v is printed by a prettyPrinter: a map, slice, array
+// or struct, possibly behind pointers, which is not an error or a Stringer.
+func isPretty(v any) bool {
+	switch v.(type) {
+	case nil, error, fmt.Stringer:
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// prettyPrinter writes values with reflection, as colored JSON-like text.
+type prettyPrinter struct {
+	e        *encoder
+	buf      *buffer
+	maxDepth int
+	maxItems int
+	// expanded prints containers on multiple lines, indented from indent.
+	expanded bool
+	indent   int
+	// seen holds the pointers and maps being printed, to detect cycles.
+	seen []uintptr
+	// path holds the groups, key and names of the fields being printed,
+	// to redact them with the Redact option.
+	path []string
+}
+
+// writePrettyKeyValue writes an attribute whose value v is printed by a
+// prettyPrinter, in buf or, with the PrettyExpanded option, in block.
+func (e *encoder) writePrettyKeyValue(buf, block *buffer, key string, groups []string, v any) {
+	p := prettyPrinter{e: e, buf: buf, maxDepth: e.opts.PrettyMaxDepth, maxItems: e.opts.PrettyMaxItems, expanded: e.opts.PrettyExpanded}
+	if p.maxDepth == 0 {
+		p.maxDepth = defaultPrettyMaxDepth
+	}
+	if p.maxItems == 0 {
+		p.maxItems = defaultPrettyMaxItems
+	}
+	var seen [defaultPrettyMaxDepth]uintptr
+	p.seen = seen[:0]
+	if e.hasRedact {
+		var path [8]string
+		p.path = append(append(path[:0], groups...), key)
+	}
+	if p.expanded {
+		e.writeBlockKey(block, key, groups)
+		p.buf, p.indent = block, 2
+		block.AppendString("  ")
+		p.write(reflect.ValueOf(v), 0)
+		block.AppendByte('\n')
+		return
+	}
+	buf.AppendByte(' ')
+	e.withColor(buf, e.keyRule(key, groups).keyStyle(e.style(ElementAttrKey)), func() {
+		e.writeKey(buf, key, groups)
+		buf.AppendByte('=')
+	})
+	p.write(reflect.ValueOf(v), 0)
+}
+
+// write writes v, nested in depth containers.
+func (p *prettyPrinter) write(v reflect.Value, depth int) {
+	e, buf := p.e, p.buf
+	switch v.Kind() {
+	case reflect.Invalid:
+		e.writeColoredString(buf, "nil", e.style(ElementAttrValueNil))
+		return
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			e.writeColoredString(buf, "nil", e.style(ElementAttrValueNil))
+			return
+		}
+	}
+	if v.CanInterface() {
+		x := v.Interface()
+		if sv := slog.AnyValue(x); sv.Kind() == slog.KindTime || sv.Kind() == slog.KindDuration {
+			e.writeValue(buf, sv, e.kindStyle(sv.Kind()))
+			return
+		}
+		switch x.(type) {
+		case error, fmt.Stringer:
+			s, style, _ := e.textValue(slog.AnyValue(x))
+			e.withColor(buf, style, func() { buf.AppendQuotedString(s) })
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		e.writeColoredBool(buf, v.Bool(), e.style(ElementAttrValueBool))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeColoredInt(buf, v.Int(), e.style(ElementAttrValueNumber))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeColoredUint(buf, v.Uint(), e.style(ElementAttrValueNumber))
+	case reflect.Float32, reflect.Float64:
+		e.writeColoredFloat(buf, v.Float(), e.style(ElementAttrValueNumber))
+	case reflect.Complex64, reflect.Complex128:
+		e.withColor(buf, e.style(ElementAttrValueNumber), func() {
+			*buf = fmt.Append(*buf, v.Complex())
+		})
+	case reflect.String:
+		e.withColor(buf, e.style(ElementAttrValueString), func() { buf.AppendQuotedString(v.String()) })
+	case reflect.Pointer:
+		if p.cycle(v.Pointer()) {
+			return
+		}
+		p.seen = append(p.seen, v.Pointer())
+		p.write(v.Elem(), depth)
+		p.seen = p.seen[:len(p.seen)-1]
+	case reflect.Interface:
+		p.write(v.Elem(), depth)
+	case reflect.Map:
+		if p.cycle(v.Pointer()) || p.tooDeep(depth, "{…}") {
+			return
+		}
+		p.seen = append(p.seen, v.Pointer())
+		keys := v.MapKeys()
+		slices.SortFunc(keys, compareMapKeys)
+		p.writeItems('{', '}', len(keys), func(i int) {
+			p.writeMapKey(keys[i])
+			buf.AppendString(": ")
+			if keys[i].Kind() == reflect.String {
+				p.writeField(keys[i].String(), v.MapIndex(keys[i]), depth+1)
+			} else {
+				p.write(v.MapIndex(keys[i]), depth+1)
+			}
+		})
+		p.seen = p.seen[:len(p.seen)-1]
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice && utf8.Valid(v.Bytes()) {
+			e.withColor(buf, e.style(ElementAttrValueString), func() { buf.AppendQuotedString(string(v.Bytes())) })
+			return
+		}
+		if p.tooDeep(depth, "[…]") {
+			return
+		}
+		p.writeItems('[', ']', v.Len(), func(i int) {
+			p.write(v.Index(i), depth+1)
+		})
+	case reflect.Struct:
+		if p.tooDeep(depth, "{…}") {
+			return
+		}
+		t := v.Type()
+		p.writeItems('{', '}', v.NumField(), func(i int) {
+			e.writeColoredString(buf, t.Field(i).Name, e.style(ElementAttrKey))
+			buf.AppendString(": ")
+			p.writeField(t.Field(i).Name, v.Field(i), depth+1)
+		})
+	default:
+		// Functions, channels and unsafe pointers
+		e.withColor(buf, e.style(ElementAttrValue), func() {
+			*buf = fmt.Appendf(*buf, "%s(%#x)", v.Type(), v.Pointer())
+		})
+	}
+}
+
+// writeField writes v, the value of the struct field or map entry named name,
+// or a redacted text if the name matches the Redact.Keys option.
+func (p *prettyPrinter) writeField(name string, v reflect.Value, depth int) {
+	e := p.e
+	if !e.hasRedact {
+		p.write(v, depth)
+		return
+	}
+	if e.redactKey(name, p.path) {
+		// The value is only needed for its hash. Unexported fields can't be
+		// converted to interfaces, but fmt prints them.
+		var value slog.Value
+		if e.opts.Redact.Hash && v.CanInterface() {
+			value = resolve(slog.AnyValue(v.Interface()))
+		} else if e.opts.Redact.Hash {
+			value = slog.StringValue(fmt.Sprint(v))
+		}
+		e.withColor(p.buf, e.style(ElementAttrValueNil), func() {
+			p.buf.AppendByte('[')
+			e.appendRedacted(p.buf, value)
+			p.buf.AppendByte(']')
+		})
+		return
+	}
+	p.path = append(p.path, name)
+	p.write(v, depth)
+	p.path = p.path[:len(p.path)-1]
+}
+
+// writeItems writes n items of a container between the open and close
+// delimiters, with writeItem, up to the maximum number of items.
+func (p *prettyPrinter) writeItems(open, close byte, n int, writeItem func(i int)) {
+	buf := p.buf
+	buf.AppendByte(open)
+	if n == 0 {
+		buf.AppendByte(close)
+		return
+	}
+	p.indent += 2
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.AppendByte(',')
+			if !p.expanded {
+				buf.AppendByte(' ')
+			}
+		}
+		p.newLine()
+		if i == p.maxItems {
+			p.e.withColor(buf, p.e.style(ElementAttrValueNil), func() {
+				buf.AppendString("…+")
+				buf.AppendInt(int64(n - i))
+			})
+			break
+		}
+		writeItem(i)
+	}
+	p.indent -= 2
+	p.newLine()
+	buf.AppendByte(close)
+}
+
+// newLine starts a new line at the current indentation, in expanded mode.
+func (p *prettyPrinter) newLine() {
+	if !p.expanded {
+		return
+	}
+	p.buf.AppendByte('\n')
+	for i := 0; i < p.indent; i++ {
+		p.buf.AppendByte(' ')
+	}
+}
+
+// writeMapKey writes a map key, unquoted if it is a string which doesn't need quoting.
+func (p *prettyPrinter) writeMapKey(k reflect.Value) {
+	if k.Kind() == reflect.String && !needsQuoting(k.String()) {
+		p.e.writeColoredString(p.buf, k.String(), p.e.style(ElementAttrKey))
+		return
+	}
+	p.write(k, p.maxDepth)
+}
+
+// cycle writes a marker and returns true if ptr is being printed.
+func (p *prettyPrinter) cycle(ptr uintptr) bool {
+	if !slices.Contains(p.seen, ptr) {
+		return false
+	}
+	p.e.writeColoredString(p.buf, "<cycle>", p.e.style(ElementAttrValueNil))
+	return true
+}
+
+// tooDeep writes marker and returns true if depth is the maximum depth.
+func (p *prettyPrinter) tooDeep(depth int, marker string) bool {
+	if depth < p.maxDepth {
+		return false
+	}
+	p.e.writeColoredString(p.buf, marker, p.e.style(ElementAttrValueNil))
+	return true
+}
+
+// compareMapKeys orders map keys, like fmt does.
+func compareMapKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		}
+		return 1
+	}
+	if a.CanInterface() && b.CanInterface() {
+		return cmp.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type prettyInner struct {
+	A int
+	b []string
+}
+
+type prettyOuter struct {
+	Name    string
+	Inner   *prettyInner
+	Tags    map[string]any
+	Err     error
+	Addr    net.IP
+	Timeout time.Duration
+	Nil     *prettyInner
+}
+
+type prettyNode struct {
+	Value int
+	Next  *prettyNode
+}
+
+func TestIsPretty(t *testing.T) {
+	m := map[string]int{}
+	for _, tc := range []struct {
+		v        any
+		expected bool
+	}{
+		{"str", false},
+		{12, false},
+		{nil, false},
+		{errors.New("err"), false},
+		{net.IPv4(1, 2, 3, 4), false},
+		{time.Second, false},
+		{(*prettyInner)(nil), false},
+		{new(*prettyInner), false},
+		{prettyNode{}, true},
+		{&prettyInner{}, true},
+		{[2]int{1, 2}, true},
+		{[]int{1}, true},
+		{m, true},
+		{&m, true},
+	} {
+		AssertEqual(t, tc.expected, isPretty(tc.v))
+	}
+}
+
+func TestHandler_PrettyValues(t *testing.T) {
+	buf := bytes.Buffer{}
+	value := prettyOuter{
+		Name:    "foo",
+		Inner:   &prettyInner{A: 1, b: []string{"x", "y z"}},
+		Tags:    map[string]any{"b": []int{1, 2}, "a": true, "with space": nil},
+		Err:     errors.New("boom"),
+		Addr:    net.IPv4(127, 0, 0, 1),
+		Timeout: 2 * time.Second,
+	}
+	opts := &HandlerOptions{NoColor: true, PrettyValues: true}
+	AssertEqual(t, `INF msg v={Name: "foo", Inner: {A: 1, b: ["x", "y z"]}, Tags: {a: true, b: [1, 2], "with space": nil}, Err: "boom", Addr: "127.0.0.1", Timeout: 2s, Nil: nil}`+"\n",
+		logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", value)))
+	AssertEqual(t, `INF msg v={Name: "foo", Inner: {A: 1, b: ["x", "y z"]}, Tags: {a: true, b: [1, 2], "with space": nil}, Err: "boom", Addr: "127.0.0.1", Timeout: 2s, Nil: nil}`+"\n",
+		logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", &value)))
+	AssertEqual(t, `INF msg v=[1, 2, 3] m={1: "a", 2: "b"} b="bytes" e=[] s=hello err=boom`+"\n",
+		logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", []int{1, 2, 3}), slog.Any("m", map[int]string{2: "b", 1: "a"}), slog.Any("b", []byte("bytes")), slog.Any("e", []int{}), slog.Any("s", "hello"), slog.Any("err", errors.New("boom"))))
+
+	// Without the option
+	AssertEqual(t, "INF msg v=\"[1 2 3]\"\n", logAttrs(t, &buf, NewHandler(&buf, &HandlerOptions{NoColor: true}), "msg", slog.Any("v", []int{1, 2, 3})))
+
+	// Fields and map entries matching Redact.Keys
+	opts.Redact.Keys = []string{"name", "*.inner.a", "*.tags.b"}
+	AssertEqual(t, `INF msg v={Name: [REDACTED], Inner: {A: [REDACTED], b: ["x", "y z"]}, Tags: {a: true, b: [REDACTED], "with space": nil}, Err: "boom", Addr: "127.0.0.1", Timeout: 2s, Nil: nil}`+"\n",
+		logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", value)))
+	opts.Redact = RedactOptions{Keys: []string{"b"}, Hash: true}
+	out := logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", value.Inner))
+	AssertEqual(t, len("INF msg v={A: 1, b: [REDACTED:123456]}\n"), len(out))
+	AssertEqual(t, true, strings.HasPrefix(out, "INF msg v={A: 1, b: [REDACTED:"))
+	AssertNotEqual(t, out, logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", &prettyInner{A: 1, b: []string{"x"}})))
+	opts.Redact = RedactOptions{}
+
+	// Limits and cycles
+	node := &prettyNode{Value: 1}
+	node.Next = &prettyNode{Value: 2, Next: node}
+	AssertEqual(t, "INF msg v={Value: 1, Next: {Value: 2, Next: <cycle>}}\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", node)))
+	self := map[string]any{}
+	self["self"] = self
+	AssertEqual(t, "INF msg v={self: <cycle>}\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", self)))
+	opts = &HandlerOptions{NoColor: true, PrettyValues: true, PrettyMaxDepth: 2, PrettyMaxItems: 3}
+	AssertEqual(t, "INF msg v=[1, 2, 3, …+2] w=[[[…]]]\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", []int{1, 2, 3, 4, 5}), slog.Any("w", [][][]int{{{1}}})))
+
+	// Expanded
+	opts = &HandlerOptions{NoColor: true, PrettyValues: true, PrettyExpanded: true}
+	AssertEqual(t, strings.Join([]string{
+		"INF msg a=1",
+		"  │ v=",
+		"  │   {",
+		"  │     A: 1,",
+		"  │     b: [",
+		"  │       \"x\",",
+		"  │       \"y\\nz\"",
+		"  │     ]",
+		"  │   }",
+		"  │ e=",
+		"  │   {}",
+		"",
+	}, "\n"), logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", prettyInner{A: 1, b: []string{"x", "y\nz"}}), slog.Int("a", 1), slog.Any("e", map[int]int{})))
+
+	// Elements are styled like attributes
+	theme := NewTheme(NewDefaultTheme()).With(ElementLevelInfo, "").With(ElementMessage, "").With(ElementAttrValueString, ToANSICode(Green))
+	opts = &HandlerOptions{ColorMode: ColorAlways, PrettyValues: true, Theme: theme}
+	AssertEqual(t, fmt.Sprintf("INF msg %[1]sv=%[2]s{%[1]sk%[2]s: [%[3]s1%[2]s, %[4]s\"s\"%[2]s, %[5]strue%[2]s, %[6]snil%[2]s]}\n",
+		theme.Style(ElementAttrKey), ResetMod, theme.styles[ElementAttrValueNumber], theme.styles[ElementAttrValueString], theme.styles[ElementAttrValueBool], theme.styles[ElementAttrValueNil]),
+		logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("v", map[string][]any{"k": {1, "s", true, nil}})))
+}
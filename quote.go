@@ -0,0 +1,67 @@
+package console
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// QuoteMode defines when attribute keys and values are quoted.
+type QuoteMode int
+
+const (
+	// QuoteAuto quotes keys and textual values only when they would otherwise
+	// be ambiguous: empty strings, or strings containing spaces, '=', '"',
+	// control characters, non-printable characters or invalid UTF-8.
+	QuoteAuto QuoteMode = iota
+	// QuoteAlways quotes all textual values (strings, errors, stringers and times).
+	// Keys are still only quoted when needed.
+	QuoteAlways
+	// QuoteNever never quotes, writing keys and values as they are.
+	QuoteNever
+)
+
+func (m QuoteMode) String() string {
+	switch m {
+	case QuoteAuto:
+		return "auto"
+	case QuoteAlways:
+		return "always"
+	case QuoteNever:
+		return "never"
+	default:
+		return "unknown"
+	}
+}
+
+// quotedASCII holds the ASCII characters which must be quoted.
+var quotedASCII = func() (set [utf8.RuneSelf]bool) {
+	for b := 0; b <= ' '; b++ {
+		set[b] = true
+	}
+	set['='], set['"'], set[0x7f] = true, true, true
+	return set
+}()
+
+// needsQuoting reports whether s must be quoted to be parsed back
+// unambiguously from a logfmt line.
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b < utf8.RuneSelf {
+			if quotedASCII[b] {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
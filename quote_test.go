@@ -0,0 +1,25 @@
+package console
+
+import "testing"
+
+func TestNeedsQuoting(t *testing.T) {
+	for s, expected := range map[string]bool{
+		"":            true,
+		"foo":         false,
+		"foo.bar":     false,
+		"héhé":        false,
+		"hello world": true,
+		"a=b":         true,
+		`"foo"`:       true,
+		`a\b`:         false,
+		"a\nb":        true,
+		"a\tb":        true,
+		"\x1b[31m":    true,
+		"\x7f":        true,
+		"\xff":        true,
+		"a\u00a0b":    true,
+		"a\u200bb":    true,
+	} {
+		AssertEqual(t, expected, needsQuoting(s))
+	}
+}
@@ -0,0 +1,237 @@
+package console
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// redactedText replaces the values of redacted attributes, between brackets.
+const redactedText = "REDACTED"
+
+// RedactOptions define the attributes whose values are hidden.
+type RedactOptions struct {
+	// Keys are patterns, as in KeyStyle.Key but ignoring case, of the keys
+	// whose values are redacted. They are matched against the key prefixed
+	// with its groups, and against the key alone, so that "password" also
+	// matches "user.password". Matching a group redacts it as a whole.
+	Keys []string
+
+	// Detectors report whether a textual value (string, error, Stringer
+	// or other non scalar value) must be redacted whatever its key,
+	// like DetectJWT, DetectBearerToken and DetectCardNumber.
+	Detectors []func(s string) bool
+
+	// Hash renders redacted values as a short hash, like "[REDACTED:4f2a9c]",
+	// so that equal values can be told apart from different ones.
+	// It is not meant to resist guessing the values, which are unsalted.
+	Hash bool
+}
+
+// cloneRedactKeys returns a lower case copy of the key patterns.
+func cloneRedactKeys(keys []string) []string {
+	keys = slices.Clone(keys)
+	for i := range keys {
+		keys[i] = strings.ToLower(keys[i])
+	}
+	return keys
+}
+
+// redactKey reports whether the key, prefixed with groups, matches one of
+// the Redact.Keys patterns.
+func (e *encoder) redactKey(key string, groups []string) bool {
+	if len(e.opts.Redact.Keys) == 0 || key == "" {
+		return false
+	}
+	var arr [maxStackKeyLen]byte
+	k := arr[:0]
+	for _, g := range groups {
+		k = appendLower(k, g)
+		k = append(k, '.')
+	}
+	k = appendLower(k, key)
+	for _, pattern := range e.opts.Redact.Keys {
+		if matchGlob(pattern, k) || matchGlob(pattern, k[len(k)-len(key):]) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendLower appends s to b, converting ASCII letters to lower case.
+func appendLower(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// redactValue reports whether the text s of a value is detected as
+// sensitive by one of the Redact.Detectors.
+func (e *encoder) redactValue(s string) bool {
+	for _, detect := range e.opts.Redact.Detectors {
+		if detect(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRedacted writes an attribute whose value is redacted.
+func (e *encoder) writeRedacted(buf *buffer, key string, groups []string, value slog.Value) {
+	buf.AppendByte(' ')
+	e.withColor(buf, e.keyRule(key, groups).keyStyle(e.style(ElementAttrKey)), func() {
+		e.writeKey(buf, key, groups)
+		buf.AppendByte('=')
+	})
+	e.withColor(buf, e.style(ElementAttrValueNil), func() {
+		quote := e.opts.QuoteMode == QuoteAlways
+		if quote {
+			buf.AppendByte('"')
+		}
+		buf.AppendByte('[')
+		e.appendRedacted(buf, value)
+		buf.AppendByte(']')
+		if quote {
+			buf.AppendByte('"')
+		}
+	})
+}
+
+// appendRedacted appends the text replacing a redacted value, followed by
+// its hash if the Redact.Hash option is set.
+func (e *encoder) appendRedacted(buf *buffer, value slog.Value) {
+	buf.AppendString(redactedText)
+	if !e.opts.Redact.Hash || value.Kind() == slog.KindGroup {
+		return
+	}
+	const digits = "0123456789abcdef"
+	buf.AppendByte(':')
+	s, _, isText := e.textValue(value)
+	h := hashValue(value, s, isText)
+	for i := 20; i >= 0; i -= 4 {
+		buf.AppendByte(digits[h>>i&0xf])
+	}
+}
+
+// DetectJWT reports whether s contains something like a JSON Web Token:
+// three base64url encoded parts separated by dots, the first two of them
+// being JSON objects.
+func DetectJWT(s string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], "eyJ")
+		if j < 0 {
+			return false
+		}
+		i += j
+		if i == 0 || !isBase64URL(s[i-1]) {
+			header := base64URLRun(s[i:])
+			rest := s[i+header:]
+			if header >= 10 && strings.HasPrefix(rest, ".eyJ") {
+				payload := base64URLRun(rest[1:])
+				if payload >= 10 && strings.HasPrefix(rest[1+payload:], ".") {
+					return true
+				}
+			}
+		}
+		i += 3
+	}
+}
+
+// isBase64URL reports whether c is a character of the base64url alphabet.
+func isBase64URL(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_'
+}
+
+// base64URLRun returns the length of the run of base64url characters starting s.
+func base64URLRun(s string) int {
+	n := 0
+	for n < len(s) && isBase64URL(s[n]) {
+		n++
+	}
+	return n
+}
+
+// DetectBearerToken reports whether s contains a bearer token, as found in
+// HTTP Authorization headers, like "Bearer mF_9.B5f-4.1JqM". Tokens must be
+// at least 8 characters long, with a digit.
+func DetectBearerToken(s string) bool {
+	const scheme = "bearer"
+	for i := 0; i+len(scheme) < len(s); i++ {
+		if !strings.EqualFold(s[i:i+len(scheme)], scheme) || i > 0 && isBase64URL(s[i-1]) {
+			continue
+		}
+		j := i + len(scheme)
+		if s[j] != ' ' {
+			continue
+		}
+		for j < len(s) && s[j] == ' ' {
+			j++
+		}
+		// token68 characters, with a digit to tell tokens from words
+		n, digit := 0, false
+		for j+n < len(s) && (isBase64URL(s[j+n]) || strings.IndexByte(".~+/", s[j+n]) >= 0) {
+			digit = digit || isDigit(s[j+n])
+			n++
+		}
+		if n >= 8 && digit {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectCardNumber reports whether s contains something like a payment card
+// number: a run of 13 to 19 digits, possibly grouped with single spaces or
+// dashes, passing the Luhn checksum.
+func DetectCardNumber(s string) bool {
+	for i := 0; i < len(s); {
+		if !isDigit(s[i]) {
+			i++
+			continue
+		}
+		var digits [19]byte
+		n := 0
+		for i < len(s) {
+			if isDigit(s[i]) {
+				if n < len(digits) {
+					digits[n] = s[i] - '0'
+				}
+				n++
+				i++
+			} else if (s[i] == ' ' || s[i] == '-') && i+1 < len(s) && isDigit(s[i+1]) {
+				i++
+			} else {
+				break
+			}
+		}
+		if n >= 13 && n <= len(digits) && luhnValid(digits[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// luhnValid reports whether digits pass the Luhn checksum.
+func luhnValid(digits []byte) bool {
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i])
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
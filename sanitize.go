@@ -0,0 +1,77 @@
+package console
+
+import "unicode/utf8"
+
+const hexDigits = "0123456789abcdef"
+
+// isUnsafeRune reports whether r can alter the terminal state or the way
+// surrounding text is displayed: C0 and C1 control characters, DEL, and
+// Unicode bidirectional formatting characters.
+func isUnsafeRune(r rune) bool {
+	switch {
+	case r < ' ', r == 0x7f:
+		return true
+	case r >= 0x80 && r <= 0x9f:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		return true
+	}
+	return false
+}
+
+// appendSanitized appends s to dst, replacing unsafe runes (see isUnsafeRune)
+// and invalid UTF-8 bytes with visible Go-like escape sequences, so that only
+// printable text reaches the terminal.
+func appendSanitized(dst []byte, s string) []byte {
+	// Fast path for printable ASCII, by far the most common text.
+	i := 0
+	for i < len(s) && s[i] >= ' ' && s[i] < 0x7f {
+		i++
+	}
+	if i == len(s) {
+		return append(dst, s...)
+	}
+	start := 0
+	for i < len(s) {
+		b := s[i]
+		if b >= ' ' && b < 0x7f {
+			i++
+			continue
+		}
+		r, size := rune(b), 1
+		if b >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(s[i:])
+			if (r != utf8.RuneError || size > 1) && !isUnsafeRune(r) {
+				i += size
+				continue
+			}
+		}
+		dst = append(dst, s[start:i]...)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, '\\', 'x', hexDigits[b>>4], hexDigits[b&0xf])
+		case r == '\a':
+			dst = append(dst, '\\', 'a')
+		case r == '\b':
+			dst = append(dst, '\\', 'b')
+		case r == '\f':
+			dst = append(dst, '\\', 'f')
+		case r == '\n':
+			dst = append(dst, '\\', 'n')
+		case r == '\r':
+			dst = append(dst, '\\', 'r')
+		case r == '\t':
+			dst = append(dst, '\\', 't')
+		case r == '\v':
+			dst = append(dst, '\\', 'v')
+		case r < utf8.RuneSelf:
+			dst = append(dst, '\\', 'x', hexDigits[b>>4], hexDigits[b&0xf])
+		default:
+			dst = append(dst, '\\', 'u',
+				hexDigits[r>>12&0xf], hexDigits[r>>8&0xf], hexDigits[r>>4&0xf], hexDigits[r&0xf])
+		}
+		i += size
+		start = i
+	}
+	return append(dst, s[start:]...)
+}
@@ -0,0 +1,25 @@
+package console
+
+import "testing"
+
+func TestAppendSanitized(t *testing.T) {
+	for s, expected := range map[string]string{
+		"":                         "",
+		"foo bar":                  "foo bar",
+		"héhé ☃":                   "héhé ☃",
+		`back\slash "quoted"`:      `back\slash "quoted"`,
+		"line1\nline2\r\n":         `line1\nline2\r\n`,
+		"a\tb\a\b\f\v":             `a\tb\a\b\f\v`,
+		"\x1b[31mred\x1b[0m":       `\x1b[31mred\x1b[0m`,
+		"\x1b]0;title\x07":         `\x1b]0;title\a`,
+		"\x00\x7f":                 `\x00\x7f`,
+		"\u009b31m":                `\u009b31m`,
+		"\x9b31m":                  `\x9b31m`,
+		"bad \xff\xfe utf8":        `bad \xff\xfe utf8`,
+		"evil\u202egnp.exe":        `evil\u202egnp.exe`,
+		"\u2066isolate\u2069":      `\u2066isolate\u2069`,
+		"valid \ufffd replacement": "valid \ufffd replacement",
+	} {
+		AssertEqual(t, expected, string(appendSanitized(nil, s)))
+	}
+}
@@ -0,0 +1,166 @@
+package console
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// colorNames holds the names of the basic colors in style specs, indexed by
+// their offset from Black, or from BrightBlack for bright ones.
+var colorNames = [2][8]string{
+	{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "gray"},
+	{"bright-black", "bright-red", "bright-green", "bright-yellow", "bright-blue", "bright-magenta", "bright-cyan", "white"},
+}
+
+// modeNames holds the names of the text modes in style specs.
+var modeNames = map[int]string{
+	Bold:       "bold",
+	Faint:      "faint",
+	Italic:     "italic",
+	Underline:  "underline",
+	CrossedOut: "crossed-out",
+}
+
+// ParseStyle parses a style spec made of space separated words, like
+// "bold cyan" or "#88c0d0 on #2e3440". Words are:
+//
+//   - text modes: bold, faint (or dim), italic, underline, crossed-out (or strikethrough)
+//   - basic colors: black, red, green, yellow, blue, magenta, cyan, gray,
+//     their bright variants prefixed with "bright-" or "bright ", and white
+//   - colors of the 256 colors palette, by index, like "208"
+//   - 24-bit colors, in hexadecimal, like "#ff8800" or "#f80"
+//   - on, setting the background to the color following it
+//   - default and none, which do nothing
+//
+// An empty spec is an empty style.
+func ParseStyle(spec string) (ANSIMod, error) {
+	var codes []int
+	bg := false
+	words := strings.Fields(strings.ToLower(spec))
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "bright" && i+1 < len(words) {
+			i++
+			word += "-" + words[i]
+		}
+		word = strings.ReplaceAll(word, "_", "-")
+		if word == "on" {
+			if bg || i+1 == len(words) {
+				return "", fmt.Errorf("invalid style %q: misplaced %q", spec, "on")
+			}
+			bg = true
+			continue
+		}
+		color, isColor := parseColorWord(word, bg)
+		switch {
+		case bg && word == "default":
+			bg = false
+			continue
+		case isColor:
+			codes = append(codes, color...)
+			bg = false
+			continue
+		case bg:
+			return "", fmt.Errorf("invalid style %q: %q is not a color", spec, word)
+		}
+		switch word {
+		case "bold":
+			codes = append(codes, Bold)
+		case "faint", "dim":
+			codes = append(codes, Faint)
+		case "italic":
+			codes = append(codes, Italic)
+		case "underline":
+			codes = append(codes, Underline)
+		case "crossed-out", "strikethrough":
+			codes = append(codes, CrossedOut)
+		case "default", "none":
+		default:
+			return "", fmt.Errorf("invalid style %q: unknown word %q", spec, word)
+		}
+	}
+	return ToANSICode(codes...), nil
+}
+
+// parseColorWord returns the SGR parameters setting the foreground,
+// or the background if bg is true, to the color named word.
+func parseColorWord(word string, bg bool) ([]int, bool) {
+	base := 38
+	if bg {
+		base = 48
+	}
+	if strings.HasPrefix(word, "#") {
+		r, g, b, err := parseHex(word)
+		if err != nil {
+			return nil, false
+		}
+		return []int{base, 2, int(r), int(g), int(b)}, true
+	}
+	if n, err := strconv.ParseUint(word, 10, 8); err == nil {
+		return []int{base, 5, int(n)}, true
+	}
+	for bright, names := range colorNames {
+		for i, name := range names {
+			if word == name {
+				return []int{basicColorCode(8*bright+i, bg)}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// FormatStyle returns the spec of style, as parsed by ParseStyle.
+// It fails if style has escape sequences other than the ones
+// ParseStyle produces.
+func FormatStyle(style ANSIMod) (string, error) {
+	var words []string
+	s := string(style)
+	for s != "" {
+		if !strings.HasPrefix(s, "\x1b[") || !strings.Contains(s, "m") {
+			return "", fmt.Errorf("cannot format style %q", style)
+		}
+		end := strings.IndexByte(s, 'm')
+		params := strings.Split(s[2:end], ";")
+		s = s[end+1:]
+		for i := 0; i < len(params); i++ {
+			p, err := strconv.Atoi(params[i])
+			if err != nil {
+				return "", fmt.Errorf("cannot format style %q", style)
+			}
+			on := ""
+			if p >= 40 && p <= 48 || p >= 100 && p <= 107 {
+				on, p = "on ", p-10
+			}
+			switch {
+			case modeNames[p] != "" && on == "":
+				words = append(words, modeNames[p])
+			case p >= 30 && p <= 37:
+				words = append(words, on+colorNames[0][p-30])
+			case p >= 90 && p <= 97:
+				words = append(words, on+colorNames[1][p-90])
+			case p == 38 && i+2 < len(params) && params[i+1] == "5":
+				n, err := strconv.ParseUint(params[i+2], 10, 8)
+				if err != nil {
+					return "", fmt.Errorf("cannot format style %q", style)
+				}
+				words = append(words, on+strconv.Itoa(int(n)))
+				i += 2
+			case p == 38 && i+4 < len(params) && params[i+1] == "2":
+				var c [3]uint8
+				for j := range c {
+					v, err := strconv.ParseUint(params[i+2+j], 10, 8)
+					if err != nil {
+						return "", fmt.Errorf("cannot format style %q", style)
+					}
+					c[j] = uint8(v)
+				}
+				words = append(words, fmt.Sprintf("%s#%02x%02x%02x", on, c[0], c[1], c[2]))
+				i += 4
+			default:
+				return "", fmt.Errorf("cannot format style %q", style)
+			}
+		}
+	}
+	return strings.Join(words, " "), nil
+}
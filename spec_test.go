@@ -0,0 +1,59 @@
+package console
+
+import (
+	"testing"
+)
+
+func TestParseStyle(t *testing.T) {
+	for spec, expected := range map[string]ANSIMod{
+		"":                         "",
+		"none":                     "",
+		"bold cyan":                ToANSICode(Bold, Cyan),
+		"Bold  Cyan":               ToANSICode(Bold, Cyan),
+		"dim italic underline":     ToANSICode(Faint, Italic, Underline),
+		"strikethrough":            ToANSICode(CrossedOut),
+		"bright-red":               ToANSICode(BrightRed),
+		"bright red on bright_red": ToANSICode(BrightRed, BgBrightRed),
+		"white on black":           ToANSICode(White, BgBlack),
+		"208":                      Color256(208),
+		"on 236":                   BgColor256(236),
+		"#88c0d0 on #2e3440":       ToANSICode(38, 2, 0x88, 0xc0, 0xd0, 48, 2, 0x2e, 0x34, 0x40),
+		"#f80":                     RGB(255, 136, 0),
+		"default on default":       "",
+	} {
+		style, err := ParseStyle(spec)
+		AssertNoError(t, err)
+		AssertEqual(t, expected, style)
+	}
+
+	for _, spec := range []string{"foo", "bold foo", "on", "red on", "on bold", "on on red", "256", "#12345", "bright", "bright bold"} {
+		_, err := ParseStyle(spec)
+		AssertError(t, err)
+	}
+}
+
+func TestFormatStyle(t *testing.T) {
+	for style, expected := range map[ANSIMod]string{
+		"":                                   "",
+		ToANSICode(Bold, Cyan):               "bold cyan",
+		ToANSICode(Faint, Italic, Underline): "faint italic underline",
+		ToANSICode(CrossedOut):               "crossed-out",
+		ToANSICode(White, BgBrightBlack):     "white on bright-black",
+		ToANSICode(Bold) + RGB(255, 136, 0) + BgColor256(236): "bold #ff8800 on 236",
+		ToANSICode(Gray, BgGray):                              "gray on gray",
+	} {
+		spec, err := FormatStyle(style)
+		AssertNoError(t, err)
+		AssertEqual(t, expected, spec)
+		parsed, err := ParseStyle(spec)
+		AssertNoError(t, err)
+		respec, err := FormatStyle(parsed)
+		AssertNoError(t, err)
+		AssertEqual(t, spec, respec)
+	}
+
+	for _, style := range []ANSIMod{ResetMod, "\x1b[m", "\x1b[1", "bold", "\x1b[38;5m", "\x1b[38;5;300m", "\x1b[38;2;1;2m", "\x1b[39m", "\x1b[1;x m"} {
+		_, err := FormatStyle(style)
+		AssertError(t, err)
+	}
+}
@@ -0,0 +1,214 @@
+package console
+
+import (
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// callersCarrier is implemented by errors carrying the program counters
+// of the call stack where they were created, as returned by runtime.Callers.
+type callersCarrier interface {
+	Callers() []uintptr
+}
+
+// framesCarrier is implemented by errors carrying the resolved frames
+// of the call stack where they were created.
+type framesCarrier interface {
+	StackFrames() []runtime.Frame
+}
+
+// stackTrace is a call stack, either as program counters or as resolved frames.
+type stackTrace struct {
+	pcs    []uintptr
+	frames []runtime.Frame
+}
+
+func (s stackTrace) empty() bool {
+	return len(s.pcs) == 0 && len(s.frames) == 0
+}
+
+// errorStackTrace returns the stack trace carried by err, if any.
+// Besides the callersCarrier and framesCarrier interfaces, errors with a
+// StackTrace method returning a slice of program counters, like the ones
+// from github.com/pkg/errors, are supported.
+func errorStackTrace(err error) stackTrace {
+	switch x := err.(type) {
+	case callersCarrier:
+		return stackTrace{pcs: x.Callers()}
+	case framesCarrier:
+		return stackTrace{frames: x.StackFrames()}
+	}
+	// The method's return type can't be named without importing its package,
+	// so it is looked up by reflection, using a constant name
+	// to let the linker keep eliminating unused methods.
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() {
+		return stackTrace{}
+	}
+	mt := m.Type()
+	if mt.NumIn() != 0 || mt.NumOut() != 1 || mt.Out(0).Kind() != reflect.Slice || mt.Out(0).Elem().Kind() != reflect.Uintptr {
+		return stackTrace{}
+	}
+	st := m.Call(nil)[0]
+	pcs := make([]uintptr, st.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(st.Index(i).Uint())
+	}
+	return stackTrace{pcs: pcs}
+}
+
+// innermostStackTrace returns the stack trace of the innermost error
+// carrying one in err's chain, which is the closest to the error's origin.
+func innermostStackTrace(err error) stackTrace {
+	var st stackTrace
+	for depth := 0; err != nil && depth < maxErrorTreeDepth; depth++ {
+		if s := errorStackTrace(err); !s.empty() {
+			st = s
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+	return st
+}
+
+// frameIterator iterates over the frames of a stackTrace.
+type frameIterator struct {
+	frames *runtime.Frames
+	list   []runtime.Frame
+}
+
+func (s stackTrace) iter() frameIterator {
+	if len(s.pcs) > 0 {
+		return frameIterator{frames: runtime.CallersFrames(s.pcs)}
+	}
+	return frameIterator{list: s.frames}
+}
+
+func (it *frameIterator) next() (runtime.Frame, bool) {
+	if it.frames != nil {
+		f, more := it.frames.Next()
+		if !more {
+			it.frames = nil
+		}
+		return f, f.PC != 0 || f.Function != ""
+	}
+	if len(it.list) == 0 {
+		return runtime.Frame{}, false
+	}
+	f := it.list[0]
+	it.list = it.list[1:]
+	return f, true
+}
+
+// isStdFrame reports whether f is in the runtime or in the standard library,
+// guessing from its package path whose first element has no dot.
+// Frames from files under the current working directory are never considered standard.
+func isStdFrame(f runtime.Frame, cwd string) bool {
+	if cwd != "" && strings.HasPrefix(f.File, cwd+string(filepath.Separator)) {
+		return false
+	}
+	fn := f.Function
+	if fn == "" {
+		return false
+	}
+	// Trim the function name, keeping only the first element of the package path.
+	if i := strings.IndexByte(fn, '/'); i >= 0 {
+		fn = fn[:i]
+	} else if i := strings.IndexByte(fn, '.'); i >= 0 {
+		fn = fn[:i]
+	}
+	return fn != "main" && !strings.Contains(fn, ".")
+}
+
+// defaultStackTraceMaxDepth is the default maximum number of frames printed in a stack trace.
+const defaultStackTraceMaxDepth = 32
+
+// callerStackTrace returns the call stack of the calling goroutine,
+// starting at the frame of pc if found, or at the caller's caller otherwise.
+// At most maxDepth frames are kept after it, plus some extra ones to report
+// how many frames are omitted.
+func callerStackTrace(pc uintptr, maxDepth int) stackTrace {
+	pcs := make([]uintptr, maxDepth+64)
+	// Skip runtime.Callers, this function and its caller
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	if i := slices.Index(pcs, pc); i >= 0 {
+		pcs = pcs[i:]
+	}
+	return stackTrace{pcs: pcs}
+}
+
+// stackTraceMaxDepth returns the maximum number of frames printed in a stack trace.
+func (e *encoder) stackTraceMaxDepth() int {
+	if e.opts.StackTraceMaxDepth > 0 {
+		return e.opts.StackTraceMaxDepth
+	}
+	return defaultStackTraceMaxDepth
+}
+
+// writeStackTrace writes the frames of st in block, one per line.
+// Consecutive frames from the standard library are dimmed and collapsed into
+// their first one, and frames beyond the maximum depth are omitted.
+func (e *encoder) writeStackTrace(block *buffer, st stackTrace, cwd string) {
+	maxDepth := e.stackTraceMaxDepth()
+	it := st.iter()
+	depth, hidden, inStd := 0, 0, false
+	for {
+		f, ok := it.next()
+		if !ok {
+			break
+		}
+		depth++
+		std := isStdFrame(f, cwd)
+		if depth > maxDepth || std && inStd {
+			hidden++
+			continue
+		}
+		e.writeHiddenFrames(block, hidden)
+		hidden, inStd = 0, std
+		if std {
+			e.writeFrame(block, f, cwd, e.style(ElementStackFrameStd), e.style(ElementStackFrameStd))
+		} else {
+			e.writeFrame(block, f, cwd, e.style(ElementStackFrame), e.style(ElementSource))
+		}
+	}
+	e.writeHiddenFrames(block, hidden)
+}
+
+// writeHiddenFrames writes a line in block telling how many frames were not printed, if any.
+func (e *encoder) writeHiddenFrames(block *buffer, n int) {
+	if n == 0 {
+		return
+	}
+	block.AppendString("    ")
+	e.withColor(block, e.style(ElementStackFrameStd), func() {
+		block.AppendString("... ")
+		block.AppendInt(int64(n))
+		block.AppendString(" more")
+	})
+	block.AppendByte('\n')
+}
+
+// writeFrame writes a line in block with the frame's function and its location.
+// Files under cwd are made relative to it.
+func (e *encoder) writeFrame(block *buffer, f runtime.Frame, cwd string, funcStyle, fileStyle ANSIMod) {
+	if cwd != "" {
+		if ff, err := filepath.Rel(cwd, f.File); err == nil && !strings.HasPrefix(ff, "..") {
+			f.File = ff
+		}
+	}
+	block.AppendString("  ")
+	e.writeColoredSanitizedString(block, f.Function, funcStyle)
+	block.AppendByte(' ')
+	e.withColor(block, fileStyle, func() {
+		e.appendSanitized(block, f.File)
+		block.AppendByte(':')
+		block.AppendInt(int64(f.Line))
+	})
+	block.AppendByte('\n')
+}
@@ -0,0 +1,42 @@
+package console
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// isTerminal reports whether fd refers to a terminal.
+func isTerminal(fd uintptr) bool {
+	var termios syscall.Termios
+	return ioctlTermios(fd, syscall.TCGETS, &termios) == 0
+}
+
+// setReadTimeout disables the canonical mode and the echo of the terminal fd,
+// so that reads return the available input, or nothing after 100ms.
+// It returns a function restoring the previous settings.
+func setReadTimeout(fd uintptr) (restore func(), ok bool) {
+	var old syscall.Termios
+	if ioctlTermios(fd, syscall.TCGETS, &old) != 0 {
+		return nil, false
+	}
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+	if ioctlTermios(fd, syscall.TCSETS, &raw) != 0 {
+		return nil, false
+	}
+	return func() { ioctlTermios(fd, syscall.TCSETS, &old) }, true
+}
+
+// openTTY opens the terminal at ttyPath for reading and writing, without
+// making it the controlling terminal of the process.
+func openTTY() (*os.File, error) {
+	return os.OpenFile(ttyPath, os.O_RDWR|syscall.O_NOCTTY, 0)
+}
+
+func ioctlTermios(fd, req uintptr, termios *syscall.Termios) syscall.Errno {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(termios)))
+	return errno
+}
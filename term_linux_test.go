@@ -0,0 +1,40 @@
+package console
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// openPTY opens a new pseudo-terminal, and returns its master and slave sides,
+// or nil files if the platform does not provide one.
+func openPTY(t *testing.T) (master, slave *os.File) {
+	t.Helper()
+	master = openTerminal(t)
+	if master == nil {
+		return nil, nil
+	}
+	var unlock, n int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		return nil, nil
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		return nil, nil
+	}
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil
+	}
+	t.Cleanup(func() { slave.Close() })
+	return master, slave
+}
+
+// termSettings returns the settings of the terminal f, as a string.
+func termSettings(t *testing.T, f *os.File) string {
+	t.Helper()
+	var termios syscall.Termios
+	AssertEqual(t, syscall.Errno(0), ioctlTermios(f.Fd(), syscall.TCGETS, &termios))
+	return fmt.Sprintf("%+v", termios)
+}
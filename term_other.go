@@ -0,0 +1,24 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package console
+
+import (
+	"errors"
+	"os"
+)
+
+// isTerminal reports whether fd refers to a terminal.
+// Terminal detection is not supported on this platform.
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+// setReadTimeout is not supported on this platform.
+func setReadTimeout(fd uintptr) (restore func(), ok bool) {
+	return nil, false
+}
+
+// openTTY is not supported on this platform.
+func openTTY() (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package console
+
+import (
+	"os"
+	"testing"
+)
+
+// openPTY returns nil files, opening pseudo-terminals is only
+// supported on Linux by the tests.
+func openPTY(t *testing.T) (master, slave *os.File) {
+	return nil, nil
+}
+
+// termSettings is never called, as openPTY returns nil files.
+func termSettings(t *testing.T, f *os.File) string {
+	return ""
+}
@@ -0,0 +1,36 @@
+package console
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// isTerminal reports whether fd refers to a console able to interpret
+// ANSI escape sequences, enabling virtual terminal processing if needed.
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	if err := syscall.GetConsoleMode(syscall.Handle(fd), &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	r, _, _ := procSetConsoleMode.Call(fd, uintptr(mode|enableVirtualTerminalProcessing))
+	return r != 0
+}
+
+// setReadTimeout is not supported on Windows, whose console input is not
+// read as a stream of bytes.
+func setReadTimeout(fd uintptr) (restore func(), ok bool) {
+	return nil, false
+}
+
+// openTTY is not supported on Windows, whose console has no /dev/tty.
+func openTTY() (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
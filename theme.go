@@ -3,6 +3,8 @@ package console
 import (
 	"fmt"
 	"log/slog"
+	"math"
+	"slices"
 )
 
 type ANSIMod string
@@ -40,6 +42,28 @@ const (
 	White
 )
 
+const (
+	BgBlack = iota + 40
+	BgRed
+	BgGreen
+	BgYellow
+	BgBlue
+	BgMagenta
+	BgCyan
+	BgGray
+)
+
+const (
+	BgBrightBlack = iota + 100
+	BgBrightRed
+	BgBrightGreen
+	BgBrightYellow
+	BgBrightBlue
+	BgBrightMagenta
+	BgBrightCyan
+	BgWhite
+)
+
 func (c ANSIMod) String() string {
 	return string(c)
 }
@@ -59,7 +83,136 @@ func ToANSICode(modes ...int) ANSIMod {
 	return ANSIMod("\x1b[" + s + "m")
 }
 
+// Color256 returns the style setting the foreground to the color n
+// of the 256 colors palette.
+func Color256(n uint8) ANSIMod {
+	return ToANSICode(38, 5, int(n))
+}
+
+// BgColor256 returns the style setting the background to the color n
+// of the 256 colors palette.
+func BgColor256(n uint8) ANSIMod {
+	return ToANSICode(48, 5, int(n))
+}
+
+// RGB returns the style setting the foreground to a 24-bit color.
+func RGB(r, g, b uint8) ANSIMod {
+	return ToANSICode(38, 2, int(r), int(g), int(b))
+}
+
+// BgRGB returns the style setting the background to a 24-bit color.
+func BgRGB(r, g, b uint8) ANSIMod {
+	return ToANSICode(48, 2, int(r), int(g), int(b))
+}
+
+// Hex returns the style setting the foreground to the 24-bit color written
+// in hexadecimal as "#rrggbb" or "#rgb", the "#" being optional.
+func Hex(s string) (ANSIMod, error) {
+	r, g, b, err := parseHex(s)
+	if err != nil {
+		return "", err
+	}
+	return RGB(r, g, b), nil
+}
+
+// BgHex returns the style setting the background to the 24-bit color written
+// in hexadecimal as "#rrggbb" or "#rgb", the "#" being optional.
+func BgHex(s string) (ANSIMod, error) {
+	r, g, b, err := parseHex(s)
+	if err != nil {
+		return "", err
+	}
+	return BgRGB(r, g, b), nil
+}
+
+// Element is a styleable element of log records.
+type Element int
+
+const (
+	ElementTimestamp Element = iota
+	ElementSource
+	ElementMessage
+	ElementMessageDebug
+	ElementAttrKey
+	ElementAttrValue
+	ElementAttrValueError
+	ElementLevelError
+	ElementLevelWarn
+	ElementLevelInfo
+	ElementLevelDebug
+	ElementStackFrame
+
+	// Styles of attribute values by kind. Empty ones fall back to the
+	// ElementAttrValue style.
+	ElementAttrValueString
+	ElementAttrValueNumber // integers and floats
+	ElementAttrValueBool
+	ElementAttrValueTime
+	ElementAttrValueDuration
+	ElementAttrValueNil // nil values and empty strings
+
+	ElementPrefix // the attribute rendered before the message, see HandlerOptions.PrefixKey
+
+	// Styles of the decorations of errors and stack traces. Empty ones fall
+	// back to the ElementTimestamp style.
+	ElementErrorTree     // the branches of trees of wrapped errors
+	ElementStackFrameStd // stack frames of the standard library, and the count of omitted ones
+
+	numElements // number of elements, keep last
+)
+
+var elementNames = [numElements]string{
+	ElementTimestamp:      "timestamp",
+	ElementSource:         "source",
+	ElementMessage:        "message",
+	ElementMessageDebug:   "message-debug",
+	ElementAttrKey:        "attr-key",
+	ElementAttrValue:      "attr-value",
+	ElementAttrValueError: "attr-value-error",
+	ElementLevelError:     "level-error",
+	ElementLevelWarn:      "level-warn",
+	ElementLevelInfo:      "level-info",
+	ElementLevelDebug:     "level-debug",
+	ElementStackFrame:     "stack-frame",
+
+	ElementAttrValueString:   "attr-value-string",
+	ElementAttrValueNumber:   "attr-value-number",
+	ElementAttrValueBool:     "attr-value-bool",
+	ElementAttrValueTime:     "attr-value-time",
+	ElementAttrValueDuration: "attr-value-duration",
+	ElementAttrValueNil:      "attr-value-nil",
+
+	ElementPrefix: "prefix",
+
+	ElementErrorTree:     "error-tree",
+	ElementStackFrameStd: "stack-frame-std",
+}
+
+func (e Element) String() string {
+	if e < 0 || e >= numElements {
+		return fmt.Sprintf("Element(%d)", int(e))
+	}
+	return elementNames[e]
+}
+
+// Theme defines the colorized output using ANSI escape sequences.
+//
+// Style returns the style of the given element, or an empty ANSIMod for
+// elements the theme doesn't style, including ones added in future versions.
+// Levels are styled with the ElementLevel* element of the closest lower
+// standard level, unless the theme also implements
+//
+//	Level(level slog.Level) ANSIMod
+//
+// to style levels individually.
 type Theme interface {
+	Name() string
+	Style(e Element) ANSIMod
+}
+
+// LegacyTheme is the method set themes implemented before the introduction
+// of Element. Use AdaptTheme to turn one into a Theme.
+type LegacyTheme interface {
 	Name() string
 	Timestamp() ANSIMod
 	Source() ANSIMod
@@ -76,76 +229,225 @@ type Theme interface {
 	Level(level slog.Level) ANSIMod
 }
 
+// AdaptTheme returns a Theme using the styles of t.
+// Stack frames are styled like attribute values, unless t also implements
+//
+//	StackFrame() ANSIMod
+func AdaptTheme(t LegacyTheme) Theme {
+	return legacyTheme{t}
+}
+
+type legacyTheme struct {
+	LegacyTheme
+}
+
+func (t legacyTheme) Style(e Element) ANSIMod {
+	switch e {
+	case ElementTimestamp:
+		return t.Timestamp()
+	case ElementSource:
+		return t.Source()
+	case ElementMessage:
+		return t.Message()
+	case ElementMessageDebug:
+		return t.MessageDebug()
+	case ElementAttrKey:
+		return t.AttrKey()
+	case ElementAttrValue:
+		return t.AttrValue()
+	case ElementAttrValueError:
+		return t.AttrValueError()
+	case ElementLevelError:
+		return t.LevelError()
+	case ElementLevelWarn:
+		return t.LevelWarn()
+	case ElementLevelInfo:
+		return t.LevelInfo()
+	case ElementLevelDebug:
+		return t.LevelDebug()
+	case ElementStackFrame:
+		if st, ok := t.LegacyTheme.(interface{ StackFrame() ANSIMod }); ok {
+			return st.StackFrame()
+		}
+		return t.AttrValue()
+	}
+	return ""
+}
+
+// ThemeDef is a Theme holding a style per element, and optionally styles
+// for individual levels. Build one with NewTheme.
+// ThemeDef values are immutable, its With* methods return modified copies.
 type ThemeDef struct {
-	name           string
-	timestamp      ANSIMod
-	source         ANSIMod
-	message        ANSIMod
-	messageDebug   ANSIMod
-	attrKey        ANSIMod
-	attrValue      ANSIMod
-	attrValueError ANSIMod
-	levelError     ANSIMod
-	levelWarn      ANSIMod
-	levelInfo      ANSIMod
-	levelDebug     ANSIMod
-}
-
-func (t ThemeDef) Name() string            { return t.name }
-func (t ThemeDef) Timestamp() ANSIMod      { return t.timestamp }
-func (t ThemeDef) Source() ANSIMod         { return t.source }
-func (t ThemeDef) Message() ANSIMod        { return t.message }
-func (t ThemeDef) MessageDebug() ANSIMod   { return t.messageDebug }
-func (t ThemeDef) AttrKey() ANSIMod        { return t.attrKey }
-func (t ThemeDef) AttrValue() ANSIMod      { return t.attrValue }
-func (t ThemeDef) AttrValueError() ANSIMod { return t.attrValueError }
-func (t ThemeDef) LevelError() ANSIMod     { return t.levelError }
-func (t ThemeDef) LevelWarn() ANSIMod      { return t.levelWarn }
-func (t ThemeDef) LevelInfo() ANSIMod      { return t.levelInfo }
-func (t ThemeDef) LevelDebug() ANSIMod     { return t.levelDebug }
+	name   string
+	styles [numElements]ANSIMod
+	levels []styledLevel // sorted by level
+}
+
+// styledLevel is a level with its own style.
+type styledLevel struct {
+	level slog.Level
+	style ANSIMod
+}
+
+// NewTheme returns a ThemeDef with the name and the styles of base,
+// to be customized with its With* methods.
+// If base is nil, the theme has no name and no styles.
+//
+//	theme := console.NewTheme(console.NewDefaultTheme()).
+//		WithName("Custom").
+//		With(console.ElementAttrKey, console.ToANSICode(console.Blue))
+func NewTheme(base Theme) ThemeDef {
+	switch base := base.(type) {
+	case nil:
+		return ThemeDef{}
+	case ThemeDef:
+		return base
+	default:
+		t := ThemeDef{name: base.Name()}
+		for e := range t.styles {
+			t.styles[e] = base.Style(Element(e))
+		}
+		return t
+	}
+}
+
+// WithName returns a copy of t named name.
+func (t ThemeDef) WithName(name string) ThemeDef {
+	t.name = name
+	return t
+}
+
+// With returns a copy of t styling the element e with style.
+// Unknown elements are ignored.
+func (t ThemeDef) With(e Element, style ANSIMod) ThemeDef {
+	if e >= 0 && e < numElements {
+		t.styles[e] = style
+	}
+	return t
+}
+
+// WithLevel returns a copy of t giving its own style to level, typically
+// a custom one like a TRACE or a FATAL level.
+// Levels without a style use the one of the closest lower level having one,
+// unless a standard level lies between them.
+func (t ThemeDef) WithLevel(level slog.Level, style ANSIMod) ThemeDef {
+	i, found := slices.BinarySearchFunc(t.levels, level, func(s styledLevel, l slog.Level) int { return int(s.level - l) })
+	t.levels = slices.Clone(t.levels)
+	if found {
+		t.levels[i].style = style
+	} else {
+		t.levels = slices.Insert(t.levels, i, styledLevel{level, style})
+	}
+	return t
+}
+
+func (t ThemeDef) Name() string { return t.name }
+
+// Style implements Theme.
+func (t ThemeDef) Style(e Element) ANSIMod {
+	if e < 0 || e >= numElements {
+		return ""
+	}
+	return t.styles[e]
+}
+
+func (t ThemeDef) Timestamp() ANSIMod      { return t.styles[ElementTimestamp] }
+func (t ThemeDef) Source() ANSIMod         { return t.styles[ElementSource] }
+func (t ThemeDef) Message() ANSIMod        { return t.styles[ElementMessage] }
+func (t ThemeDef) MessageDebug() ANSIMod   { return t.styles[ElementMessageDebug] }
+func (t ThemeDef) AttrKey() ANSIMod        { return t.styles[ElementAttrKey] }
+func (t ThemeDef) AttrValue() ANSIMod      { return t.styles[ElementAttrValue] }
+func (t ThemeDef) AttrValueError() ANSIMod { return t.styles[ElementAttrValueError] }
+func (t ThemeDef) LevelError() ANSIMod     { return t.styles[ElementLevelError] }
+func (t ThemeDef) LevelWarn() ANSIMod      { return t.styles[ElementLevelWarn] }
+func (t ThemeDef) LevelInfo() ANSIMod      { return t.styles[ElementLevelInfo] }
+func (t ThemeDef) LevelDebug() ANSIMod     { return t.styles[ElementLevelDebug] }
+func (t ThemeDef) StackFrame() ANSIMod     { return t.styles[ElementStackFrame] }
+
 func (t ThemeDef) Level(level slog.Level) ANSIMod {
+	var standard slog.Level
+	var style ANSIMod
 	switch {
 	case level >= slog.LevelError:
-		return t.LevelError()
+		standard, style = slog.LevelError, t.LevelError()
 	case level >= slog.LevelWarn:
-		return t.LevelWarn()
+		standard, style = slog.LevelWarn, t.LevelWarn()
 	case level >= slog.LevelInfo:
-		return t.LevelInfo()
+		standard, style = slog.LevelInfo, t.LevelInfo()
+	case level >= slog.LevelDebug:
+		standard, style = slog.LevelDebug, t.LevelDebug()
 	default:
-		return t.LevelDebug()
+		standard, style = math.MinInt, t.LevelDebug()
 	}
+	for i := len(t.levels) - 1; i >= 0; i-- {
+		if l := t.levels[i]; l.level <= level {
+			if l.level >= standard {
+				return l.style
+			}
+			break
+		}
+	}
+	return style
 }
 
-func NewDefaultTheme() Theme {
-	return ThemeDef{
-		name:           "Default",
-		timestamp:      ToANSICode(BrightBlack),
-		source:         ToANSICode(Bold, BrightBlack),
-		message:        ToANSICode(Bold),
-		messageDebug:   ToANSICode(),
-		attrKey:        ToANSICode(Cyan),
-		attrValue:      ToANSICode(),
-		attrValueError: ToANSICode(Bold, Red),
-		levelError:     ToANSICode(Red),
-		levelWarn:      ToANSICode(Yellow),
-		levelInfo:      ToANSICode(Green),
-		levelDebug:     ToANSICode(),
-	}
+func NewDefaultTheme() ThemeDef {
+	return NewTheme(nil).
+		WithName("Default").
+		With(ElementTimestamp, ToANSICode(BrightBlack)).
+		With(ElementSource, ToANSICode(Bold, BrightBlack)).
+		With(ElementMessage, ToANSICode(Bold)).
+		With(ElementMessageDebug, ToANSICode()).
+		With(ElementAttrKey, ToANSICode(Cyan)).
+		With(ElementAttrValue, ToANSICode()).
+		With(ElementAttrValueError, ToANSICode(Bold, Red)).
+		With(ElementLevelError, ToANSICode(Red)).
+		With(ElementLevelWarn, ToANSICode(Yellow)).
+		With(ElementLevelInfo, ToANSICode(Green)).
+		With(ElementLevelDebug, ToANSICode()).
+		With(ElementStackFrame, ToANSICode(Magenta)).
+		With(ElementAttrValueNumber, ToANSICode(BrightBlue)).
+		With(ElementAttrValueBool, ToANSICode(Magenta)).
+		With(ElementAttrValueTime, ToANSICode(Blue)).
+		With(ElementAttrValueDuration, ToANSICode(Blue)).
+		With(ElementAttrValueNil, ToANSICode(BrightBlack)).
+		With(ElementPrefix, ToANSICode(Bold, Blue)).
+		With(ElementErrorTree, ToANSICode(BrightBlack)).
+		With(ElementStackFrameStd, ToANSICode(BrightBlack))
+}
+
+func NewBrightTheme() ThemeDef {
+	return NewTheme(nil).
+		WithName("Bright").
+		With(ElementTimestamp, ToANSICode(Gray)).
+		With(ElementSource, ToANSICode(Bold, Gray)).
+		With(ElementMessage, ToANSICode(Bold, White)).
+		With(ElementMessageDebug, ToANSICode()).
+		With(ElementAttrKey, ToANSICode(BrightCyan)).
+		With(ElementAttrValue, ToANSICode()).
+		With(ElementAttrValueError, ToANSICode(Bold, BrightRed)).
+		With(ElementLevelError, ToANSICode(BrightRed)).
+		With(ElementLevelWarn, ToANSICode(BrightYellow)).
+		With(ElementLevelInfo, ToANSICode(BrightGreen)).
+		With(ElementLevelDebug, ToANSICode()).
+		With(ElementStackFrame, ToANSICode(BrightMagenta)).
+		With(ElementAttrValueNumber, ToANSICode(BrightBlue)).
+		With(ElementAttrValueBool, ToANSICode(BrightMagenta)).
+		With(ElementAttrValueTime, ToANSICode(Blue)).
+		With(ElementAttrValueDuration, ToANSICode(Blue)).
+		With(ElementAttrValueNil, ToANSICode(Gray)).
+		With(ElementPrefix, ToANSICode(Bold, BrightBlue)).
+		With(ElementErrorTree, ToANSICode(Gray)).
+		With(ElementStackFrameStd, ToANSICode(Gray))
 }
 
-func NewBrightTheme() Theme {
-	return ThemeDef{
-		name:           "Bright",
-		timestamp:      ToANSICode(Gray),
-		source:         ToANSICode(Bold, Gray),
-		message:        ToANSICode(Bold, White),
-		messageDebug:   ToANSICode(),
-		attrKey:        ToANSICode(BrightCyan),
-		attrValue:      ToANSICode(),
-		attrValueError: ToANSICode(Bold, BrightRed),
-		levelError:     ToANSICode(BrightRed),
-		levelWarn:      ToANSICode(BrightYellow),
-		levelInfo:      ToANSICode(BrightGreen),
-		levelDebug:     ToANSICode(),
+// WithLevelStyles returns a theme based on theme, giving their own style to the
+// levels in styles, typically custom ones like a TRACE or a FATAL level.
+// Levels without a style use the one of the closest lower level having one,
+// unless a standard level lies between them.
+func WithLevelStyles(theme Theme, styles map[slog.Level]ANSIMod) ThemeDef {
+	t := NewTheme(theme)
+	for l, style := range styles {
+		t = t.WithLevel(l, style)
 	}
+	return t
 }
@@ -0,0 +1,136 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNewTheme(t *testing.T) {
+	base := NewDefaultTheme()
+	blue := ToANSICode(Blue)
+	theme := NewTheme(base).WithName("Custom").With(ElementAttrKey, blue)
+	AssertEqual(t, "Custom", theme.Name())
+	AssertEqual(t, blue, theme.Style(ElementAttrKey))
+	AssertEqual(t, base.Message(), theme.Style(ElementMessage))
+	// base is left untouched
+	AssertEqual(t, "Default", base.Name())
+	AssertEqual(t, ToANSICode(Cyan), base.Style(ElementAttrKey))
+
+	AssertEqual(t, ANSIMod(""), theme.Style(numElements))
+	AssertEqual(t, ANSIMod(""), theme.Style(-1))
+	AssertEqual(t, theme.styles, theme.With(numElements, blue).styles)
+	AssertEqual(t, [numElements]ANSIMod{}, NewTheme(nil).styles)
+
+	// Levels styles are copied on write
+	trace := theme.WithLevel(-8, blue)
+	AssertEqual(t, blue, trace.Level(-8))
+	AssertEqual(t, base.LevelDebug(), theme.Level(-8))
+	AssertEqual(t, blue, trace.WithLevel(-12, ToANSICode(Red)).Level(-8))
+	AssertEqual(t, ToANSICode(Red), trace.WithLevel(-8, ToANSICode(Red)).Level(-8))
+	AssertEqual(t, blue, trace.Level(-8))
+}
+
+func TestElement_String(t *testing.T) {
+	AssertEqual(t, "attr-key", ElementAttrKey.String())
+	AssertEqual(t, "stack-frame", ElementStackFrame.String())
+	AssertEqual(t, "Element(42)", Element(42).String())
+	for e := Element(0); e < numElements; e++ {
+		AssertNotEqual(t, "", e.String())
+	}
+}
+
+// styleTheme is a Theme only styling messages.
+type styleTheme struct{}
+
+func (styleTheme) Name() string { return "Style" }
+func (styleTheme) Style(e Element) ANSIMod {
+	if e == ElementMessage {
+		return ToANSICode(Underline)
+	}
+	return ""
+}
+
+// oldTheme implements the method set of themes prior to Element.
+type oldTheme struct{ ThemeDef }
+
+func (t oldTheme) Level(slog.Level) ANSIMod { return ToANSICode(Blue) }
+
+func TestHandler_CustomTheme(t *testing.T) {
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Theme: styleTheme{}})
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)
+	rec.AddAttrs(slog.Int("foo", 12))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, fmt.Sprintf("INF %sfoobar%s foo=12\n", ToANSICode(Underline), ResetMod), buf.String())
+
+	theme := AdaptTheme(oldTheme{NewDefaultTheme()})
+	AssertEqual(t, "Default", theme.Name())
+	for e := Element(0); e <= ElementStackFrame; e++ {
+		AssertEqual(t, NewDefaultTheme().Style(e), theme.Style(e))
+	}
+	// Elements added since fall back to the AttrValue style
+	AssertEqual(t, ANSIMod(""), theme.Style(ElementAttrValueNumber))
+	AssertEqual(t, ANSIMod(""), theme.Style(numElements))
+
+	buf.Reset()
+	h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Theme: theme})
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, fmt.Sprintf("%[1]sINF%[2]s %[3]sfoobar%[2]s %[4]sfoo=%[2]s12\n", ToANSICode(Blue), ResetMod, ToANSICode(Bold), ToANSICode(Cyan)), buf.String())
+}
+
+func TestHandler_KindStyles(t *testing.T) {
+	str, number, boolean := ToANSICode(Green), ToANSICode(Blue), ToANSICode(Magenta)
+	tm, duration, null, value := ToANSICode(Yellow), ToANSICode(Cyan), ToANSICode(Faint), ToANSICode(Italic)
+	theme := NewTheme(nil).
+		With(ElementAttrValue, value).
+		With(ElementAttrValueString, str).
+		With(ElementAttrValueNumber, number).
+		With(ElementAttrValueBool, boolean).
+		With(ElementAttrValueTime, tm).
+		With(ElementAttrValueDuration, duration).
+		With(ElementAttrValueNil, null)
+	var nilPtr *int
+	for _, tc := range []struct {
+		value slog.Value
+		text  string
+		style ANSIMod
+	}{
+		{slog.StringValue("foo"), "foo", str},
+		{slog.StringValue(""), `""`, null},
+		{slog.IntValue(-12), "-12", number},
+		{slog.Uint64Value(12), "12", number},
+		{slog.Float64Value(1.5), "1.5", number},
+		{slog.BoolValue(true), "true", boolean},
+		{slog.TimeValue(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), `"2024-01-02 03:04:05"`, tm},
+		{slog.DurationValue(time.Second), "1s", duration},
+		{slog.AnyValue(nil), "<nil>", null},
+		{slog.AnyValue(nilPtr), "<nil>", null},
+		{slog.AnyValue((*panickingStringer)(nil)), "<nil>", null},
+		{slog.AnyValue(struct{ A int }{1}), "{1}", value},
+		{slog.AnyValue(errors.New("boom")), "boom", ""},
+	} {
+		buf := bytes.Buffer{}
+		h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Theme: theme})
+		rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+		rec.AddAttrs(slog.Any("foo", tc.value))
+		AssertNoError(t, h.Handle(context.Background(), rec))
+		expected := tc.text
+		if tc.style != "" {
+			expected = string(tc.style) + tc.text + string(ResetMod)
+		}
+		AssertEqual(t, "INF msg foo="+expected+"\n", buf.String())
+	}
+
+	// Empty kind styles fall back to the AttrValue style
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Theme: NewTheme(nil).With(ElementAttrValue, value)})
+	rec := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	rec.AddAttrs(slog.Int("foo", 12), slog.String("bar", ""))
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	AssertEqual(t, fmt.Sprintf("INF msg foo=%[1]s12%[2]s bar=%[1]s\"\"%[2]s\n", value, ResetMod), buf.String())
+}
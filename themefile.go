@@ -0,0 +1,282 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ThemeEnv is the environment variable holding the name of a registered
+// theme, or the path of a theme file, used when HandlerOptions.Theme is nil.
+// NewHandler silently ignores an unknown name or an invalid file, and falls
+// back to the theme it would use without the variable. Call ThemeFromEnv to
+// get the error.
+const ThemeEnv = "CONSOLE_SLOG_THEME"
+
+// parseElement returns the element named name.
+func parseElement(name string) (Element, bool) {
+	for e, n := range elementNames {
+		if n == name {
+			return Element(e), true
+		}
+	}
+	return 0, false
+}
+
+// parseThemeLevel parses the level of a level style, either an integer or a
+// standard level name with an optional offset, like "ERROR+4".
+func parseThemeLevel(s string) (slog.Level, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return slog.Level(n), nil
+	}
+	var l slog.Level
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
+
+// set sets the property key of a theme definition, which is either "name",
+// "base" for the registered theme the definition is based on, an element
+// name, or "level:" followed by a level.
+func (t *ThemeDef) set(key, value string) error {
+	switch {
+	case key == "name":
+		t.name = value
+		return nil
+	case key == "base":
+		base, ok := LookupTheme(value)
+		if !ok {
+			return fmt.Errorf("unknown theme %q", value)
+		}
+		*t = NewTheme(base)
+		return nil
+	}
+	style, err := ParseStyle(value)
+	if err != nil {
+		return err
+	}
+	if l, ok := strings.CutPrefix(key, "level:"); ok {
+		level, err := parseThemeLevel(l)
+		if err != nil {
+			return fmt.Errorf("invalid theme level %q: %w", l, err)
+		}
+		*t = t.WithLevel(level, style)
+		return nil
+	}
+	e, ok := parseElement(key)
+	if !ok {
+		return fmt.Errorf("unknown theme element %q", key)
+	}
+	*t = t.With(e, style)
+	return nil
+}
+
+// properties returns the properties of t, as (key, spec) pairs.
+// Elements without a style are omitted.
+func (t ThemeDef) properties() ([][2]string, error) {
+	props := [][2]string{{"name", t.name}}
+	for e, style := range t.styles {
+		if style == "" {
+			continue
+		}
+		spec, err := FormatStyle(style)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", Element(e), err)
+		}
+		props = append(props, [2]string{Element(e).String(), spec})
+	}
+	for _, l := range t.levels {
+		spec, err := FormatStyle(l.style)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.level, err)
+		}
+		props = append(props, [2]string{"level:" + l.level.String(), spec})
+	}
+	return props, nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// The theme is written as semicolon separated key=spec pairs, like
+//
+//	name=Custom;timestamp=faint;attr-key=bold cyan;level:ERROR+4=bold white on red
+//
+// where specs are formatted with FormatStyle.
+func (t ThemeDef) MarshalText() ([]byte, error) {
+	props, err := t.properties()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for i, p := range props {
+		if i > 0 {
+			buf.WriteByte(';')
+		}
+		buf.WriteString(p[0])
+		buf.WriteByte('=')
+		buf.WriteString(p[1])
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It accepts the format written by MarshalText, in which a "base" key can name
+// a registered theme whose styles are used for the elements not listed,
+// or just the name of a registered theme.
+func (t *ThemeDef) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if !strings.Contains(s, "=") {
+		theme, ok := LookupTheme(s)
+		if !ok {
+			return fmt.Errorf("unknown theme %q", s)
+		}
+		*t = NewTheme(theme)
+		return nil
+	}
+	var props [][2]string
+	for _, prop := range strings.Split(s, ";") {
+		if strings.TrimSpace(prop) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(prop, "=")
+		if !ok {
+			return fmt.Errorf("invalid theme property %q", prop)
+		}
+		p := [2]string{strings.TrimSpace(key), strings.TrimSpace(value)}
+		if p[0] == "base" {
+			// The base is applied first, so that other properties override it.
+			props = append([][2]string{p}, props...)
+		} else {
+			props = append(props, p)
+		}
+	}
+	def := ThemeDef{}
+	for _, p := range props {
+		if err := def.set(p[0], p[1]); err != nil {
+			return err
+		}
+	}
+	*t = def
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+// The theme is written as an object with a "name" key, a key per element
+// holding its style spec, formatted with FormatStyle, and a "levels" object
+// holding the styles of individual levels, if any.
+func (t ThemeDef) MarshalJSON() ([]byte, error) {
+	props, err := t.properties()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	levels := false
+	for i, p := range props {
+		key := p[0]
+		if l, ok := strings.CutPrefix(key, "level:"); ok {
+			if !levels {
+				buf.WriteString(`,"levels":{`)
+				levels = true
+			} else {
+				buf.WriteByte(',')
+			}
+			key = l
+		} else if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(p[1])
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	if levels {
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// It accepts the format written by MarshalJSON, in which a "base" key can name
+// a registered theme whose styles are used for the elements not listed,
+// or a string as accepted by UnmarshalText.
+func (t *ThemeDef) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return t.UnmarshalText([]byte(s))
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(data, &props); err != nil {
+		return err
+	}
+	def := ThemeDef{}
+	// The base is applied first, so that other properties override it.
+	if raw, ok := props["base"]; ok {
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("theme base: %w", err)
+		}
+		if err := def.set("base", s); err != nil {
+			return err
+		}
+		delete(props, "base")
+	}
+	for key, raw := range props {
+		if key == "levels" {
+			var levels map[string]string
+			if err := json.Unmarshal(raw, &levels); err != nil {
+				return fmt.Errorf("theme levels: %w", err)
+			}
+			for l, spec := range levels {
+				if err := def.set("level:"+l, spec); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return fmt.Errorf("theme element %s: %w", key, err)
+		}
+		if err := def.set(key, s); err != nil {
+			return err
+		}
+	}
+	*t = def
+	return nil
+}
+
+// LoadThemeFile loads a theme from a JSON file, in the format written by
+// ThemeDef.MarshalJSON.
+func LoadThemeFile(path string) (ThemeDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ThemeDef{}, err
+	}
+	var t ThemeDef
+	if err := json.Unmarshal(data, &t); err != nil {
+		return ThemeDef{}, fmt.Errorf("loading theme %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// ThemeFromEnv returns the theme set by the CONSOLE_SLOG_THEME environment
+// variable, either the name of a registered theme, or the path of a theme
+// file loaded with LoadThemeFile. It returns a nil Theme if the variable is
+// not set.
+func ThemeFromEnv() (Theme, error) {
+	v := os.Getenv(ThemeEnv)
+	if v == "" {
+		return nil, nil
+	}
+	if theme, ok := LookupTheme(v); ok {
+		return theme, nil
+	}
+	theme, err := LoadThemeFile(v)
+	if err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
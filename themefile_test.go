@@ -0,0 +1,129 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestThemeDef_MarshalText(t *testing.T) {
+	theme := NewTheme(nil).
+		WithName("Custom").
+		With(ElementTimestamp, ToANSICode(Faint)).
+		With(ElementAttrKey, ToANSICode(Bold, Cyan)).
+		WithLevel(12, ToANSICode(Bold, White, BgRed))
+	text, err := theme.MarshalText()
+	AssertNoError(t, err)
+	AssertEqual(t, "name=Custom;timestamp=faint;attr-key=bold cyan;level:ERROR+4=bold white on red", string(text))
+
+	var parsed ThemeDef
+	AssertNoError(t, parsed.UnmarshalText(text))
+	AssertEqual(t, "Custom", parsed.Name())
+	AssertEqual(t, theme.styles, parsed.styles)
+	AssertEqual(t, theme.Level(12), parsed.Level(12))
+
+	AssertNoError(t, parsed.UnmarshalText([]byte("attr-key = 208 ; base = solarized-dark; name=Mine")))
+	AssertEqual(t, "Mine", parsed.Name())
+	AssertEqual(t, Color256(208), parsed.Style(ElementAttrKey))
+	AssertEqual(t, NewSolarizedDarkTheme().Message(), parsed.Style(ElementMessage))
+
+	AssertNoError(t, parsed.UnmarshalText([]byte("Bright")))
+	AssertEqual(t, "Bright", parsed.Name())
+	AssertEqual(t, NewBrightTheme().styles, parsed.styles)
+
+	for _, s := range []string{"unknown", "base=unknown", "foo=bold", "attr-key=foo", "level:FOO=bold", "attr-key"} {
+		AssertError(t, parsed.UnmarshalText([]byte(s)))
+	}
+	_, err = NewTheme(nil).With(ElementMessage, ResetMod).MarshalText()
+	AssertError(t, err)
+}
+
+func TestThemeDef_MarshalJSON(t *testing.T) {
+	for _, registered := range Themes() {
+		theme := NewTheme(registered).WithLevel(-8, ToANSICode(Faint))
+		data, err := json.Marshal(theme)
+		AssertNoError(t, err)
+		var parsed ThemeDef
+		AssertNoError(t, json.Unmarshal(data, &parsed))
+		// Styles may be encoded differently once parsed, compare their specs.
+		expected, _ := theme.MarshalText()
+		text, err := parsed.MarshalText()
+		AssertNoError(t, err)
+		AssertEqual(t, string(expected), string(text))
+	}
+
+	data, err := json.Marshal(NewTheme(nil).WithName("Custom").With(ElementAttrKey, ToANSICode(Bold, Cyan)))
+	AssertNoError(t, err)
+	AssertEqual(t, `{"name":"Custom","attr-key":"bold cyan"}`, string(data))
+
+	var parsed ThemeDef
+	AssertNoError(t, json.Unmarshal([]byte(`{"base":"Default","name":"Nord","message":"bold #88c0d0 on #2e3440","levels":{"-8":"faint","ERROR+4":"bold red"}}`), &parsed))
+	AssertEqual(t, "Nord", parsed.Name())
+	AssertEqual(t, ToANSICode(Bold, 38, 2, 0x88, 0xc0, 0xd0, 48, 2, 0x2e, 0x34, 0x40), parsed.Style(ElementMessage))
+	AssertEqual(t, NewDefaultTheme().AttrKey(), parsed.Style(ElementAttrKey))
+	AssertEqual(t, ToANSICode(Faint), parsed.Level(-8))
+	AssertEqual(t, ToANSICode(Bold, Red), parsed.Level(12))
+
+	AssertNoError(t, json.Unmarshal([]byte(`"solarized-light"`), &parsed))
+	AssertEqual(t, "SolarizedLight", parsed.Name())
+
+	for _, s := range []string{`"unknown"`, `{"base":"unknown"}`, `{"base":1}`, `{"foo":"bold"}`, `{"message":"foo"}`, `{"message":1}`, `{"levels":{"FOO":"bold"}}`, `{"levels":[]}`, `[]`} {
+		AssertError(t, json.Unmarshal([]byte(s), &parsed))
+	}
+}
+
+func TestLoadThemeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.json")
+	AssertNoError(t, os.WriteFile(path, []byte(`{"base":"Default","name":"File","message":"underline"}`), 0o600))
+	theme, err := LoadThemeFile(path)
+	AssertNoError(t, err)
+	AssertEqual(t, "File", theme.Name())
+	AssertEqual(t, ToANSICode(Underline), theme.Style(ElementMessage))
+
+	_, err = LoadThemeFile(filepath.Join(t.TempDir(), "missing.json"))
+	AssertError(t, err)
+	AssertNoError(t, os.WriteFile(path, []byte(`{"message":"foo"}`), 0o600))
+	_, err = LoadThemeFile(path)
+	AssertError(t, err)
+}
+
+func TestThemeFromEnv(t *testing.T) {
+	t.Setenv(ThemeEnv, "")
+	theme, err := ThemeFromEnv()
+	AssertNoError(t, err)
+	AssertEqual(t, nil, theme)
+
+	t.Setenv(ThemeEnv, "high-contrast")
+	theme, err = ThemeFromEnv()
+	AssertNoError(t, err)
+	AssertEqual(t, "HighContrast", theme.Name())
+
+	path := filepath.Join(t.TempDir(), "theme.json")
+	AssertNoError(t, os.WriteFile(path, []byte(`{"name":"File","message":"underline"}`), 0o600))
+	t.Setenv(ThemeEnv, path)
+	theme, err = ThemeFromEnv()
+	AssertNoError(t, err)
+	AssertEqual(t, "File", theme.Name())
+
+	buf := bytes.Buffer{}
+	h := NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways})
+	AssertNoError(t, h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "foobar", 0)))
+	AssertEqual(t, fmt.Sprintf("INF %sfoobar%s\n", ToANSICode(Underline), ResetMod), buf.String())
+
+	// An explicit theme takes precedence
+	h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways, Theme: NewBrightTheme()})
+	AssertEqual(t, "Bright", h.opts.Theme.Name())
+
+	t.Setenv(ThemeEnv, "unknown")
+	theme, err = ThemeFromEnv()
+	AssertError(t, err)
+	AssertEqual(t, nil, theme)
+	h = NewHandler(&buf, &HandlerOptions{ColorMode: ColorAlways})
+	AssertEqual(t, "Default", h.opts.Theme.Name())
+}
@@ -0,0 +1,234 @@
+package console
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+var registry = struct {
+	sync.RWMutex
+	themes map[string]Theme
+}{themes: make(map[string]Theme)}
+
+func init() {
+	for _, theme := range []Theme{
+		NewDefaultTheme(),
+		NewBrightTheme(),
+		NewLightTheme(),
+		NewSolarizedDarkTheme(),
+		NewSolarizedLightTheme(),
+		NewHighContrastTheme(),
+		NewColorBlindTheme(),
+		NewMonochromeTheme(),
+	} {
+		RegisterTheme(theme)
+	}
+}
+
+// themeKey normalizes a theme name for lookups, which ignore case,
+// spaces, dashes and underscores.
+func themeKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// RegisterTheme makes theme available by its name through LookupTheme,
+// replacing any theme registered with the same name.
+// It panics if the name of theme is empty.
+func RegisterTheme(theme Theme) {
+	key := themeKey(theme.Name())
+	if key == "" {
+		panic("console: RegisterTheme called with an unnamed theme")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.themes[key] = theme
+}
+
+// LookupTheme returns the registered theme named name, and reports whether
+// there is one. Names are matched ignoring case, spaces, dashes and
+// underscores, so that "solarized-dark" finds the "SolarizedDark" theme.
+func LookupTheme(name string) (Theme, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	theme, ok := registry.themes[themeKey(name)]
+	return theme, ok
+}
+
+// Themes returns the registered themes, sorted by name.
+func Themes() []Theme {
+	registry.RLock()
+	defer registry.RUnlock()
+	themes := make([]Theme, 0, len(registry.themes))
+	for _, theme := range registry.themes {
+		themes = append(themes, theme)
+	}
+	slices.SortFunc(themes, func(a, b Theme) int { return strings.Compare(a.Name(), b.Name()) })
+	return themes
+}
+
+// NewLightTheme returns a theme for terminals with a light background.
+func NewLightTheme() ThemeDef {
+	return NewTheme(nil).
+		WithName("Light").
+		With(ElementTimestamp, Color256(243)).
+		With(ElementSource, ToANSICode(Bold)+Color256(240)).
+		With(ElementMessage, ToANSICode(Bold, Black)).
+		With(ElementMessageDebug, ToANSICode()).
+		With(ElementAttrKey, Color256(25)).
+		With(ElementAttrValue, ToANSICode()).
+		With(ElementAttrValueError, ToANSICode(Bold)+Color256(124)).
+		With(ElementLevelError, Color256(160)).
+		With(ElementLevelWarn, Color256(136)).
+		With(ElementLevelInfo, Color256(28)).
+		With(ElementLevelDebug, ToANSICode()).
+		With(ElementStackFrame, Color256(90)).
+		With(ElementAttrValueNumber, Color256(31)).
+		With(ElementAttrValueBool, Color256(127)).
+		With(ElementAttrValueTime, Color256(61)).
+		With(ElementAttrValueDuration, Color256(61)).
+		With(ElementAttrValueNil, Color256(245)).
+		With(ElementPrefix, ToANSICode(Bold)+Color256(24)).
+		With(ElementErrorTree, Color256(243)).
+		With(ElementStackFrameStd, Color256(243))
+}
+
+// Solarized palette, see https://ethanschoonover.com/solarized/
+var (
+	solarizedBase01  = [3]uint8{0x58, 0x6e, 0x75}
+	solarizedBase00  = [3]uint8{0x65, 0x7b, 0x83}
+	solarizedBase0   = [3]uint8{0x83, 0x94, 0x96}
+	solarizedBase1   = [3]uint8{0x93, 0xa1, 0xa1}
+	solarizedYellow  = [3]uint8{0xb5, 0x89, 0x00}
+	solarizedOrange  = [3]uint8{0xcb, 0x4b, 0x16}
+	solarizedRed     = [3]uint8{0xdc, 0x32, 0x2f}
+	solarizedMagenta = [3]uint8{0xd3, 0x36, 0x82}
+	solarizedViolet  = [3]uint8{0x6c, 0x71, 0xc4}
+	solarizedBlue    = [3]uint8{0x26, 0x8b, 0xd2}
+	solarizedCyan    = [3]uint8{0x2a, 0xa1, 0x98}
+	solarizedGreen   = [3]uint8{0x85, 0x99, 0x00}
+)
+
+func rgb(c [3]uint8) ANSIMod { return RGB(c[0], c[1], c[2]) }
+
+// NewSolarizedDarkTheme returns a theme using the Solarized palette,
+// for terminals with a dark background.
+func NewSolarizedDarkTheme() ThemeDef {
+	return newSolarizedTheme("SolarizedDark", solarizedBase01, solarizedBase0, solarizedBase1)
+}
+
+// NewSolarizedLightTheme returns a theme using the Solarized palette,
+// for terminals with a light background.
+func NewSolarizedLightTheme() ThemeDef {
+	return newSolarizedTheme("SolarizedLight", solarizedBase1, solarizedBase00, solarizedBase01)
+}
+
+// newSolarizedTheme returns a Solarized theme with the given secondary,
+// body and emphasized content colors.
+func newSolarizedTheme(name string, secondary, body, emphasized [3]uint8) ThemeDef {
+	return NewTheme(nil).
+		WithName(name).
+		With(ElementTimestamp, rgb(secondary)).
+		With(ElementSource, ToANSICode(Bold)+rgb(secondary)).
+		With(ElementMessage, ToANSICode(Bold)+rgb(emphasized)).
+		With(ElementMessageDebug, rgb(body)).
+		With(ElementAttrKey, rgb(solarizedBlue)).
+		With(ElementAttrValue, rgb(body)).
+		With(ElementAttrValueError, ToANSICode(Bold)+rgb(solarizedRed)).
+		With(ElementLevelError, rgb(solarizedRed)).
+		With(ElementLevelWarn, rgb(solarizedYellow)).
+		With(ElementLevelInfo, rgb(solarizedGreen)).
+		With(ElementLevelDebug, rgb(solarizedViolet)).
+		With(ElementStackFrame, rgb(solarizedMagenta)).
+		With(ElementAttrValueNumber, rgb(solarizedCyan)).
+		With(ElementAttrValueBool, rgb(solarizedOrange)).
+		With(ElementAttrValueTime, rgb(solarizedViolet)).
+		With(ElementAttrValueDuration, rgb(solarizedViolet)).
+		With(ElementAttrValueNil, rgb(secondary)).
+		With(ElementPrefix, ToANSICode(Bold)+rgb(solarizedCyan)).
+		With(ElementErrorTree, rgb(secondary)).
+		With(ElementStackFrameStd, rgb(secondary))
+}
+
+// NewHighContrastTheme returns a theme with bright colors, and levels
+// highlighted with a background color.
+func NewHighContrastTheme() ThemeDef {
+	return NewTheme(nil).
+		WithName("HighContrast").
+		With(ElementTimestamp, ToANSICode(White)).
+		With(ElementSource, ToANSICode(Bold, White)).
+		With(ElementMessage, ToANSICode(Bold, White)).
+		With(ElementMessageDebug, ToANSICode(White)).
+		With(ElementAttrKey, ToANSICode(Bold, BrightCyan)).
+		With(ElementAttrValue, ToANSICode(White)).
+		With(ElementAttrValueError, ToANSICode(Bold, Underline, BrightRed)).
+		With(ElementLevelError, ToANSICode(Bold, White, BgRed)).
+		With(ElementLevelWarn, ToANSICode(Bold, Black, BgBrightYellow)).
+		With(ElementLevelInfo, ToANSICode(Bold, Black, BgBrightGreen)).
+		With(ElementLevelDebug, ToANSICode(Bold, Black, BgWhite)).
+		With(ElementStackFrame, ToANSICode(Bold, BrightMagenta)).
+		With(ElementAttrValueNumber, ToANSICode(BrightYellow)).
+		With(ElementAttrValueBool, ToANSICode(BrightMagenta)).
+		With(ElementAttrValueTime, ToANSICode(BrightBlue)).
+		With(ElementAttrValueDuration, ToANSICode(BrightBlue)).
+		With(ElementAttrValueNil, ToANSICode(Italic, White)).
+		With(ElementPrefix, ToANSICode(Bold, Underline, White)).
+		With(ElementErrorTree, ToANSICode(White)).
+		With(ElementStackFrameStd, ToANSICode(White))
+}
+
+// NewColorBlindTheme returns a theme using the Okabe-Ito palette, whose colors
+// remain distinguishable with the common forms of color blindness.
+func NewColorBlindTheme() ThemeDef {
+	return NewTheme(nil).
+		WithName("ColorBlind").
+		With(ElementTimestamp, ToANSICode(BrightBlack)).
+		With(ElementSource, ToANSICode(Bold, BrightBlack)).
+		With(ElementMessage, ToANSICode(Bold)).
+		With(ElementMessageDebug, ToANSICode()).
+		With(ElementAttrKey, RGB(0x56, 0xb4, 0xe9)).
+		With(ElementAttrValue, ToANSICode()).
+		With(ElementAttrValueError, ToANSICode(Bold)+RGB(0xd5, 0x5e, 0x00)).
+		With(ElementLevelError, ToANSICode(Bold)+RGB(0xd5, 0x5e, 0x00)).
+		With(ElementLevelWarn, RGB(0xf0, 0xe4, 0x42)).
+		With(ElementLevelInfo, RGB(0x00, 0x9e, 0x73)).
+		With(ElementLevelDebug, ToANSICode()).
+		With(ElementStackFrame, RGB(0xcc, 0x79, 0xa7)).
+		With(ElementAttrValueNumber, RGB(0xe6, 0x9f, 0x00)).
+		With(ElementAttrValueBool, RGB(0xcc, 0x79, 0xa7)).
+		With(ElementAttrValueTime, RGB(0x00, 0x9e, 0x73)).
+		With(ElementAttrValueDuration, RGB(0x00, 0x9e, 0x73)).
+		With(ElementAttrValueNil, ToANSICode(BrightBlack)).
+		With(ElementPrefix, ToANSICode(Bold)+RGB(0x56, 0xb4, 0xe9)).
+		With(ElementErrorTree, ToANSICode(BrightBlack)).
+		With(ElementStackFrameStd, ToANSICode(BrightBlack))
+}
+
+// NewMonochromeTheme returns a theme without colors, using bold and faint
+// text only.
+func NewMonochromeTheme() ThemeDef {
+	return NewTheme(nil).
+		WithName("Monochrome").
+		With(ElementTimestamp, ToANSICode(Faint)).
+		With(ElementSource, ToANSICode(Faint)).
+		With(ElementMessage, ToANSICode(Bold)).
+		With(ElementMessageDebug, ToANSICode()).
+		With(ElementAttrKey, ToANSICode(Faint)).
+		With(ElementAttrValue, ToANSICode()).
+		With(ElementAttrValueError, ToANSICode(Bold)).
+		With(ElementLevelError, ToANSICode(Bold)).
+		With(ElementLevelWarn, ToANSICode(Bold)).
+		With(ElementLevelInfo, ToANSICode()).
+		With(ElementLevelDebug, ToANSICode(Faint)).
+		With(ElementStackFrame, ToANSICode()).
+		With(ElementAttrValueNil, ToANSICode(Faint)).
+		With(ElementPrefix, ToANSICode(Bold)).
+		With(ElementErrorTree, ToANSICode(Faint)).
+		With(ElementStackFrameStd, ToANSICode(Faint))
+}
@@ -0,0 +1,57 @@
+package console
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThemeRegistry(t *testing.T) {
+	var names []string
+	for _, theme := range Themes() {
+		names = append(names, theme.Name())
+	}
+	AssertEqual(t, "Bright,ColorBlind,Default,HighContrast,Light,Monochrome,SolarizedDark,SolarizedLight", strings.Join(names, ","))
+
+	for _, name := range []string{"SolarizedDark", "solarized-dark", "Solarized Dark", "SOLARIZED_DARK"} {
+		theme, ok := LookupTheme(name)
+		AssertEqual(t, true, ok)
+		AssertEqual(t, "SolarizedDark", theme.Name())
+	}
+	_, ok := LookupTheme("unknown")
+	AssertEqual(t, false, ok)
+	_, ok = LookupTheme("")
+	AssertEqual(t, false, ok)
+
+	custom := NewTheme(NewDefaultTheme()).WithName("Test Custom")
+	RegisterTheme(custom)
+	t.Cleanup(func() {
+		registry.Lock()
+		delete(registry.themes, themeKey(custom.Name()))
+		registry.Unlock()
+	})
+	theme, ok := LookupTheme("test-custom")
+	AssertEqual(t, true, ok)
+	AssertEqual(t, "Test Custom", theme.Name())
+	AssertEqual(t, 9, len(Themes()))
+
+	RegisterTheme(custom.WithName("TestCustom").With(ElementMessage, ToANSICode(Italic)))
+	theme, _ = LookupTheme("Test Custom")
+	AssertEqual(t, ToANSICode(Italic), theme.Style(ElementMessage))
+	AssertEqual(t, 9, len(Themes()))
+
+	defer func() {
+		AssertNotEqual(t, nil, recover())
+	}()
+	RegisterTheme(NewTheme(nil).WithName(" - "))
+}
+
+func TestMonochromeTheme(t *testing.T) {
+	theme := NewMonochromeTheme()
+	for e := Element(0); e < numElements; e++ {
+		for _, c := range theme.Style(e) {
+			if c == '3' || c == '4' || c == '9' {
+				t.Errorf("%s is colored: %q", e, theme.Style(e))
+			}
+		}
+	}
+}
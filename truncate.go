@@ -0,0 +1,90 @@
+package console
+
+import "strconv"
+
+// truncationMark starts the suffix of truncated values and messages.
+const truncationMark = "…"
+
+// KeyMaxLen overrides the MaxValueLen option for the attributes whose key
+// matches a pattern.
+type KeyMaxLen struct {
+	// Key is the pattern matched against the attribute key, as in KeyStyle.Key.
+	Key string
+
+	// MaxLen is the maximum length of the values, as in MaxValueLen.
+	// If zero, the values are never truncated.
+	MaxLen int
+}
+
+// maxValueLen returns the maximum length of the values of the key
+// prefixed with groups.
+func (e *encoder) maxValueLen(key string, groups []string) int {
+	if len(e.opts.KeyMaxValueLens) == 0 {
+		return e.opts.MaxValueLen
+	}
+	var arr [maxStackKeyLen]byte
+	k := appendDottedKey(arr[:0], key, groups)
+	for _, r := range e.opts.KeyMaxValueLens {
+		if matchGlob(r.Key, k) {
+			return r.MaxLen
+		}
+	}
+	return e.opts.MaxValueLen
+}
+
+// truncate returns s truncated to maxLen runes, if maxLen is positive,
+// and the number of bytes cut off.
+func truncate(s string, maxLen int) (string, int) {
+	if maxLen <= 0 || len(s) <= maxLen {
+		return s, 0
+	}
+	n := 0
+	for i := range s {
+		if n == maxLen {
+			return s[:i], len(s) - i
+		}
+		n++
+	}
+	return s, 0
+}
+
+// writeTruncationMark writes the suffix of a text truncated by omitted bytes,
+// like "…(+12.3KB)", in the style of nil values.
+func (e *encoder) writeTruncationMark(buf *buffer, omitted int) {
+	if omitted <= 0 {
+		return
+	}
+	e.withColor(buf, e.style(ElementAttrValueNil), func() {
+		buf.AppendString(truncationMark)
+		buf.AppendString("(+")
+		appendByteSize(buf, int64(omitted))
+		buf.AppendByte(')')
+	})
+}
+
+// writeBlockTruncationMark writes the suffix of a text truncated by omitted
+// bytes at the end of the last line of block.
+func (e *encoder) writeBlockTruncationMark(block *buffer, omitted int) {
+	if omitted <= 0 {
+		return
+	}
+	*block = (*block)[:block.Len()-1] // Newline
+	e.writeTruncationMark(block, omitted)
+	block.AppendByte('\n')
+}
+
+// appendByteSize appends n bytes in a human readable form, like "123B" or "12.3KB".
+func appendByteSize(buf *buffer, n int64) {
+	if n < 1000 {
+		buf.AppendInt(n)
+		buf.AppendByte('B')
+		return
+	}
+	size, unit := float64(n)/1024, 0
+	for size >= 1000 && unit < 3 {
+		size /= 1024
+		unit++
+	}
+	*buf = strconv.AppendFloat(*buf, size, 'f', 1, 64)
+	buf.AppendString([...]string{"KB", "MB", "GB", "TB"}[unit])
+}
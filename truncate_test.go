@@ -0,0 +1,82 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTruncate(t *testing.T) {
+	for _, tc := range []struct {
+		s        string
+		maxLen   int
+		expected string
+		omitted  int
+	}{
+		{"hello", 0, "hello", 0},
+		{"hello", 5, "hello", 0},
+		{"hello", 10, "hello", 0},
+		{"hello", 3, "hel", 2},
+		{"héllo", 5, "héllo", 0},
+		{"héllo", 2, "hé", 3},
+		{"日本語です", 3, "日本語", 6},
+		{"", 3, "", 0},
+	} {
+		s, omitted := truncate(tc.s, tc.maxLen)
+		AssertEqual(t, tc.expected, s)
+		AssertEqual(t, tc.omitted, omitted)
+	}
+}
+
+func TestAppendByteSize(t *testing.T) {
+	for n, expected := range map[int64]string{
+		0:             "0B",
+		999:           "999B",
+		1000:          "1.0KB",
+		12595:         "12.3KB",
+		999 * 1024:    "999.0KB",
+		1023 * 1024:   "1.0MB",
+		1024 * 1024:   "1.0MB",
+		3 << 30:       "3.0GB",
+		5 << 40:       "5.0TB",
+		5000000 << 30: "4882.8TB",
+	} {
+		buf := buffer{}
+		appendByteSize(&buf, n)
+		AssertEqual(t, expected, buf.String())
+	}
+}
+
+func TestHandler_Truncate(t *testing.T) {
+	buf := bytes.Buffer{}
+	long := strings.Repeat("x", 12600)
+
+	opts := &HandlerOptions{NoColor: true, MaxValueLen: 5, MaxMessageLen: 8, KeyMaxValueLens: []KeyMaxLen{{Key: "body", MaxLen: 2}, {Key: "*.raw"}}}
+	AssertEqual(t, "INF message msg=short\n", logAttrs(t, &buf, NewHandler(&buf, opts), "message", slog.String("msg", "short")))
+	AssertEqual(t, "INF a long m…(+6B) a=hello…(+6B)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "a long message", slog.String("a", "hello world")))
+	AssertEqual(t, "INF msg a=xxxxx…(+12.3KB)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("a", long)))
+	AssertEqual(t, "INF msg a=\"a b c\"…(+4B) b=日本語です…(+3B)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("a", "a b c d e"), slog.String("b", "日本語ですね")))
+	AssertEqual(t, "INF msg err=\"conn \"…(+13B)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Any("err", errors.New("conn reset by peer"))))
+	AssertEqual(t, "INF msg n=1234567890 d=1m40s\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.Int("n", 1234567890), slog.Duration("d", 100*time.Second)))
+	AssertEqual(t, "INF msg body=he…(+9B) g.raw=\"hello world\"\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("body", "hello world"), slog.Group("g", slog.String("raw", "hello world"))))
+
+	// Truncated values are quoted on their own
+	opts.QuoteMode = QuoteAlways
+	AssertEqual(t, "INF msg a=\"hello\"…(+6B)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("a", "hello world")))
+	opts.QuoteMode = QuoteAuto
+
+	// The mark ends the last line of multi-line values and messages
+	opts.Multiline = true
+	AssertEqual(t, "INF msg\n  │ a=\n  │   ab\n  │   cd…(+5B)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("a", "ab\ncdef\ngh")))
+	AssertEqual(t, "INF ab\n  │ cdefg…(+3B)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "ab\ncdefg\nhi"))
+	AssertEqual(t, "INF abcdefgh…(+3B)\n", logAttrs(t, &buf, NewHandler(&buf, opts), "abcdefgh\nhi"))
+
+	// The mark is styled like nil values
+	theme := NewDefaultTheme()
+	opts = &HandlerOptions{ColorMode: ColorAlways, MaxValueLen: 2, Theme: NewTheme(theme).With(ElementLevelInfo, "").With(ElementMessage, "")}
+	AssertEqual(t, fmt.Sprintf("INF msg %[1]sa=%[3]sab%[2]s…(+1B)%[3]s\n", theme.AttrKey(), theme.styles[ElementAttrValueNil], ResetMod), logAttrs(t, &buf, NewHandler(&buf, opts), "msg", slog.String("a", "abc")))
+}
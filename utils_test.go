@@ -1,8 +1,12 @@
 package console
 
 import (
+	"bytes"
 	"cmp"
+	"context"
+	"log/slog"
 	"testing"
+	"time"
 )
 
 func AssertZero[E comparable](t *testing.T, v E) {
@@ -55,6 +59,23 @@ func AssertError(t *testing.T, err error) {
 // 	}
 // }
 
+// logAttrs resets buf and lets h, writing to buf, handle an info record
+// without time, with the message msg and attrs. It returns what h wrote.
+func logAttrs(t *testing.T, buf *bytes.Buffer, h slog.Handler, msg string, attrs ...slog.Attr) string {
+	t.Helper()
+	return logRecord(t, buf, h, slog.NewRecord(time.Time{}, slog.LevelInfo, msg, 0), attrs...)
+}
+
+// logRecord resets buf and lets h, writing to buf, handle rec with attrs
+// added. It returns what h wrote.
+func logRecord(t *testing.T, buf *bytes.Buffer, h slog.Handler, rec slog.Record, attrs ...slog.Attr) string {
+	t.Helper()
+	buf.Reset()
+	rec.AddAttrs(attrs...)
+	AssertNoError(t, h.Handle(context.Background(), rec))
+	return buf.String()
+}
+
 type writerFunc func([]byte) (int, error)
 
 func (w writerFunc) Write(b []byte) (int, error) {